@@ -1,36 +1,51 @@
 package cloudtm
 
 import (
-	"bytes"
-	"encoding/json"
+	"bufio"
+	"errors"
 	"fmt"
-	"io"
 	"os"
-	"os/exec"
 	"path/filepath"
-	"regexp"
-	"time"
+	"strings"
 
 	"github.com/raxkumar/cloudtm/helper"
 	"github.com/spf13/cobra"
 )
 
 var autoApprove bool
+var applyCompression string
+var applyMessage string
+var applyTF terraformFlags
 
 var applyCmd = &cobra.Command{
 	Use:   "apply",
 	Short: "apply infrastructure changes (wrapper around Terraform apply)",
 	Long: `Applies Terraform infrastructure changes and snapshots state if any change occurs.
 Behaviors:
-- 'cloudtm apply' runs interactively like Terraform.
-- 'cloudtm apply --auto-approve' skips manual approval automatically.`,
+- 'cloudtm apply' runs 'terraform plan -out', asks for approval and applies the saved plan.
+- 'cloudtm apply --auto-approve' skips manual approval automatically.
+
+Terraform is driven with -json so change counts, per-resource actions and
+diagnostics are read from its machine-readable output. The binary plan and its
+'terraform show -json' rendering are stored in .cloudtm/versions/vN/.
+
+When the apply fails part-way, the project and the partially updated state are
+still captured as a version marked "failed", with Terraform's error output in
+.cloudtm/versions/vN/error.log, so there is a record of what actually changed.
+
+Variables, targets and other plan options are forwarded to Terraform: use the
+flags below or pass any Terraform flag after "--". The arguments and var-files
+are recorded in the version's metadata and replayed by rollback.
+
+Usage:
+    cloudtm apply --var-file prod.tfvars --var 'region=eu-west-1'
+    cloudtm apply --target aws_instance.web --parallelism 20
+    cloudtm apply -- -lock-timeout=5m -refresh=false`,
+	Args:        terraformPassthroughArgs,
+	Annotations: map[string]string{annotationTerraform: "true", annotationMutating: "true"},
 	Run: func(cmd *cobra.Command, args []string) {
-		// Step 1: Ensure Terraform exists
-		if _, err := exec.LookPath("terraform"); err != nil {
-			fmt.Println("❌ Terraform not found in PATH.")
-			fmt.Println("Please install Terraform: https://developer.hashicorp.com/terraform/downloads")
-			os.Exit(1)
-		}
+		// Step 1: Select the Terraform-compatible binary
+		requireBinary()
 
 		// Step 2: Verify CloudTimeMachine directories
 		cwd, _ := os.Getwd()
@@ -40,107 +55,267 @@ Behaviors:
 
 		if _, err := os.Stat(cloudtmDir); os.IsNotExist(err) {
 			fmt.Println("❌ CloudTimeMachine not initialized. Run: cloudtm init")
-			os.Exit(1)
+			exit(1)
 		}
 		os.MkdirAll(versionDir, 0755)
 		os.MkdirAll(metaDir, 0755)
 
-		// Step 3: Build Terraform command
-		tfArgs := []string{"apply"}
-		if autoApprove {
-			tfArgs = append(tfArgs, "--auto-approve")
-			fmt.Println("🚀 Running 'terraform apply --auto-approve'...")
-		} else {
-			fmt.Println("🚀 Running 'terraform apply' (interactive)...")
+		compression := snapshotCompression(cloudtmDir, applyCompression)
+		if err := helper.ValidateCompression(compression); err != nil {
+			fmt.Println("❌", err)
+			exit(1)
 		}
+		store := localStore(cloudtmDir)
 
-		tfCmd := exec.Command("terraform", tfArgs...)
+		warnLineageChange(cloudtmDir, liveStateInfo(cwd))
 
-		// Step 4: Stream + capture output simultaneously
-		var outputBuf bytes.Buffer
-		mw := io.MultiWriter(os.Stdout, &outputBuf)
-		tfCmd.Stdout = mw
-		tfCmd.Stderr = mw
-		tfCmd.Stdin = os.Stdin
-
-		// Step 5: Run Terraform
-		if err := tfCmd.Run(); err != nil {
+		// Step 3: Plan, approve and apply the saved plan
+		tfArgs := applyTF.args(cmd, args)
+		applied, err := planAndApply(cwd, cloudtmDir, autoApprove, tfArgs)
+		defer os.Remove(pendingPlanFile(cloudtmDir))
+		if errors.Is(err, errApplyCancelled) {
+			fmt.Println("\n❌ Apply cancelled.")
+			return
+		}
+		if err != nil {
 			fmt.Println("❌ Terraform apply failed:", err)
-			os.Exit(1)
+			if applied != nil {
+				// The apply started, so resources and state may have changed
+				recordFailedApply(store, cloudtmDir, cwd, compression, applied, func(meta *helper.Metadata) {
+					meta.Message = strings.TrimSpace(applyMessage)
+					meta.Inputs = helper.NewTerraformInputs(tfArgs)
+				})
+			}
+			os.Remove(pendingPlanFile(cloudtmDir))
+			exit(1)
+		}
+		if applied == nil {
+			// Nothing to apply
+			return
+		}
+
+		// Step 4: Analyze structured results for changes
+		summary := applied.Result.Summary
+		if summary == nil {
+			planSummary := applied.Plan.Summary()
+			summary = &planSummary
 		}
 
-		// Step 6: Analyze output for changes
-		output := outputBuf.String()
-		re := regexp.MustCompile(`Resources: (\d+) added, (\d+) changed, (\d+) destroyed`)
-		matches := re.FindStringSubmatch(output)
-
-		if len(matches) == 4 {
-			added := matches[1]
-			changed := matches[2]
-			destroyed := matches[3]
-
-			if added != "0" || changed != "0" || destroyed != "0" {
-				// Step 7: Snapshot logic
-				files, _ := os.ReadDir(versionDir)
-				nextVersion := fmt.Sprintf("v%d", len(files)+1)
-
-				// Create version directory structure
-				versionPath := filepath.Join(versionDir, nextVersion)
-				tfConfigsPath := filepath.Join(versionPath, "tf_configs")
-				if err := os.MkdirAll(tfConfigsPath, 0755); err != nil {
-					fmt.Println("⚠️ Failed to create version directory:", err)
-					return
-				}
-
-				// Copy entire project directory excluding .terraform, .cloudtm, and unnecessary files
-				excludeDirs := []string{".terraform", ".cloudtm"}
-				excludeFiles := []string{"terraform.tfstate.backup"}
-				excludePatterns := []string{"*.log", "*.tmp"}
-				if err := helper.CopyDirectory(cwd, tfConfigsPath, excludeDirs, excludeFiles, excludePatterns); err != nil {
-					fmt.Println("⚠️ Failed to copy project files:", err)
-					return
-				}
-
-				// Create metadata JSON
-				metaDest := filepath.Join(metaDir, nextVersion+".json")
-				meta := map[string]interface{}{
-					"version":   nextVersion,
-					"timestamp": time.Now().UTC().Format(time.RFC3339),
-					"resources": map[string]string{
-						"added":     added,
-						"changed":   changed,
-						"destroyed": destroyed,
-					},
-				}
-
-				metaJSON, _ := json.MarshalIndent(meta, "", "  ")
-				if err := os.WriteFile(metaDest, metaJSON, 0644); err != nil {
-					fmt.Println("⚠️ Failed to write metadata file:", err)
-					return
-				}
-
-				// Update current.json
-				if err := helper.UpdateCurrentVersion(cloudtmDir, nextVersion, true); err != nil {
-					fmt.Println("⚠️ Failed to update current.json:", err)
-					return
-				}
-
-				fmt.Printf("\n📦 Snapshot created: %s\n", nextVersion)
-				fmt.Printf("🗂  Saved configs: %s\n", tfConfigsPath)
-				fmt.Printf("🧾 Metadata: %s\n", metaDest)
-				fmt.Printf("✅ Updated current version to: %s\n", nextVersion)
-			} else {
-				fmt.Println("✅ No resource changes detected — skipping snapshot.")
+		if summary.HasChanges() {
+			// Step 5: Snapshot the project as a new version
+			stateInfo := liveStateInfo(cwd)
+			newVersion, err := createVersion(store, cloudtmDir, cwd, compression, applied, func(meta *helper.Metadata) {
+				meta.Resources = helper.ResourceCounts{Added: summary.Add, Changed: summary.Change, Destroyed: summary.Remove}
+				meta.Changes = applied.Plan.Changes()
+				meta.Message = strings.TrimSpace(applyMessage)
+				meta.State = stateInfo
+				meta.Inputs = helper.NewTerraformInputs(tfArgs)
+			})
+			if err != nil {
+				fmt.Println("⚠️ Failed to create version:", err)
+				return
 			}
+
+			event := helper.NewHistoryEvent(helper.EventApply)
+			event.Version = newVersion
+			event.Resources = helper.SummaryCounts(summary)
+			event.Message = strings.TrimSpace(applyMessage)
+			recordEvent(cloudtmDir, event)
 		} else {
-			fmt.Println("⚠️ Could not parse Terraform output for resource changes.")
+			fmt.Println("✅ No resource changes detected — skipping snapshot.")
 		}
 
 		fmt.Println("\n✅ Terraform apply completed successfully.")
 	},
 }
 
+// appliedPlan is the outcome of a plan-then-apply run
+type appliedPlan struct {
+	Result   *helper.RunResult
+	Plan     *helper.Plan
+	PlanFile string
+	PlanJSON []byte
+	Err      error // Set when the apply failed after it started
+}
+
+// pendingPlanFile is where the plan is saved until it is applied and moved into a version
+func pendingPlanFile(cloudtmDir string) string {
+	return filepath.Join(cloudtmDir, "pending.tfplan")
+}
+
+// errApplyCancelled is returned by planAndApply when the plan is not approved
+var errApplyCancelled = errors.New("apply cancelled")
+
+// planAndApply runs 'terraform plan -json -out', renders the saved plan with
+// 'terraform show -json', asks for approval unless approve is set and applies
+// the saved plan. tfArgs are passed to the plan, and those Terraform accepts
+// with a saved plan (e.g. -parallelism) to the apply. A nil result means there
+// was nothing to apply. When the apply itself fails, the result is returned
+// with the error so the partial apply can be recorded.
+func planAndApply(cwd, cloudtmDir string, approve bool, tfArgs []string) (*appliedPlan, error) {
+	planFile := pendingPlanFile(cloudtmDir)
+
+	fmt.Println("🚀 Running 'terraform plan -json -out'...")
+	planArgs := append([]string{"plan", "-json", "-input=false", "-out=" + planFile}, tfArgs...)
+	planResult, err := helper.RunTerraformJSON(cwd, planArgs, os.Stdout)
+	if err != nil {
+		return nil, err
+	}
+	if !planResult.Summary.HasChanges() {
+		fmt.Println("\n✅ No resource changes planned — nothing to apply.")
+		return nil, nil
+	}
+
+	planJSON, err := helper.OutputTerraform(cwd, "show", "-json", planFile)
+	if err != nil {
+		return nil, fmt.Errorf("terraform show -json: %w", err)
+	}
+	plan, err := helper.ParsePlan(planJSON)
+	if err != nil {
+		return nil, fmt.Errorf("parsing plan JSON: %w", err)
+	}
+
+	if !approve && !confirm("\nDo you want to perform these actions?\n  Only 'yes' will be accepted to approve.\n\n  Enter a value: ") {
+		return nil, errApplyCancelled
+	}
+
+	fmt.Println("\n🚀 Running 'terraform apply -json' on the saved plan...")
+	applyArgs := append([]string{"apply", "-json", "-input=false"}, helper.SavedPlanArgs(tfArgs)...)
+	result, err := helper.RunTerraformJSON(cwd, append(applyArgs, planFile), os.Stdout)
+	applied := &appliedPlan{Result: result, Plan: plan, PlanFile: planFile, PlanJSON: planJSON}
+	if err != nil {
+		if result == nil {
+			// Terraform did not start
+			return nil, err
+		}
+		applied.Err = err
+		return applied, err
+	}
+	return applied, nil
+}
+
+// recordFailedApply snapshots the project after a failed apply as a version
+// marked failed, with the changes Terraform completed, its error output and
+// the partially updated state. fill sets further metadata fields.
+func recordFailedApply(store helper.SnapshotStore, cloudtmDir, cwd, compression string, applied *appliedPlan, fill func(meta *helper.Metadata)) {
+	fmt.Println("\n📸 Capturing the partially applied state...")
+	summary := applied.Result.AppliedSummary()
+	stateInfo := liveStateInfo(cwd)
+	var source string
+	newVersion, err := createVersion(store, cloudtmDir, cwd, compression, applied, func(meta *helper.Metadata) {
+		meta.Status = helper.StatusFailed
+		meta.Errors = applied.Result.Errors()
+		meta.Resources = helper.ResourceCounts{Added: summary.Add, Changed: summary.Change, Destroyed: summary.Remove}
+		meta.Changes = applied.Result.Applied
+		meta.State = stateInfo
+		fill(meta)
+		source = meta.RestoredFrom
+	})
+	if err != nil {
+		fmt.Println("⚠️ Failed to create version:", err)
+		return
+	}
+
+	event := helper.NewHistoryEvent(helper.EventApplyFailed)
+	event.Version = newVersion
+	event.Source = source
+	event.Resources = helper.SummaryCounts(&summary)
+	event.Message = applied.Err.Error()
+	if errs := applied.Result.Errors(); len(errs) > 0 {
+		event.Message = errs[0].Summary
+	}
+	recordEvent(cloudtmDir, event)
+}
+
+// createVersion snapshots the project as a new version, stores the applied
+// plan with it (when there is one), writes its metadata and makes it the
+// current version. fill sets the version-specific metadata fields.
+//
+// The version is written to .cloudtm/staging/ and renamed into versions/ once
+// complete, and current.json is updated last, so an interrupted run never
+// leaves current.json pointing at a partial version.
+func createVersion(store helper.SnapshotStore, cloudtmDir, cwd, compression string, applied *appliedPlan, fill func(meta *helper.Metadata)) (string, error) {
+	nextVersion, err := helper.NextVersion(cloudtmDir)
+	if err != nil {
+		return "", fmt.Errorf("allocating version number: %w", err)
+	}
+	staged := helper.StagingStore(store, nextVersion)
+
+	// Copy entire project directory excluding .terraform, .cloudtm, and unnecessary files
+	snapshot, err := helper.CreateSnapshot(staged, cwd, nextVersion, compression)
+	if err != nil {
+		return "", fmt.Errorf("copying project files: %w", err)
+	}
+
+	// Keep the binary plan and its JSON rendering with the version, the
+	// variables it was planned with and Terraform's error output when the
+	// apply failed
+	if applied != nil {
+		if err := helper.SavePlan(staged, nextVersion, applied.PlanFile, applied.PlanJSON, helper.Encrypts(store)); err != nil {
+			fmt.Println("⚠️ Failed to save plan files:", err)
+		}
+		if sensitive := applied.Plan.SensitiveVariables(); len(sensitive) > 0 && !helper.Encrypts(store) {
+			fmt.Printf("⚠️  Warning: %s stores the values of sensitive variables (%s) unencrypted.\n", helper.PlanFileName, strings.Join(sensitive, ", "))
+			fmt.Println("   Encrypt versions to protect them: cloudtm keygen <key-file> && cloudtm rekey --new-key-file <key-file>")
+		}
+		if vars := applied.Plan.CaptureVariables(helper.Encrypts(store)); vars != nil {
+			if err := helper.SaveVariables(staged, nextVersion, vars); err != nil {
+				fmt.Println("⚠️ Failed to save variables:", err)
+			}
+		}
+		if applied.Err != nil {
+			if err := helper.SaveErrorLog(staged, nextVersion, helper.FailureLog(applied.Result, applied.Err)); err != nil {
+				fmt.Println("⚠️ Failed to save error output:", err)
+			}
+		}
+	}
+
+	meta := helper.NewMetadata(nextVersion)
+	meta.Binary = activeBinary
+	fill(meta)
+	sealVersion(staged, cloudtmDir, meta)
+	if err := helper.CommitStagedVersion(cloudtmDir, nextVersion); err != nil {
+		return "", fmt.Errorf("moving version into place: %w", err)
+	}
+	metaDest, err := helper.SaveMetadata(cloudtmDir, meta)
+	if err != nil {
+		return "", fmt.Errorf("writing metadata file: %w", err)
+	}
+
+	// A failed apply may have left nothing deployed
+	deployed := true
+	if meta.Failed() {
+		empty, err := helper.IsStateEmpty(cwd)
+		deployed = err == nil && !empty
+	}
+	if err := helper.UpdateCurrentVersion(cloudtmDir, nextVersion, deployed); err != nil {
+		return "", fmt.Errorf("updating current.json: %w", err)
+	}
+
+	fmt.Printf("\n📦 Snapshot created: %s\n", nextVersion)
+	fmt.Printf("🗂  Saved configs: %s\n", snapshot)
+	if applied != nil {
+		fmt.Printf("📋 Saved plan: %s\n", filepath.Join(cloudtmDir, "versions", nextVersion, helper.PlanJSONFileName))
+	}
+	if meta.Failed() {
+		fmt.Printf("⚠️  Marked failed; error output: %s\n", filepath.Join(cloudtmDir, "versions", nextVersion, helper.ErrorLogFileName))
+	}
+	fmt.Printf("🧾 Metadata: %s\n", metaDest)
+	fmt.Printf("✅ Updated current version to: %s\n", nextVersion)
+	return nextVersion, nil
+}
+
+// confirm prints prompt and reports whether the user answered "yes"
+func confirm(prompt string) bool {
+	fmt.Print(prompt)
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	return strings.TrimSpace(answer) == "yes"
+}
+
 func init() {
 	applyCmd.Flags().BoolVar(&autoApprove, "auto-approve", false, "Skip interactive approval")
+	applyCmd.Flags().StringVarP(&applyMessage, "message", "m", "", "Message describing the new version")
+	applyTF.register(applyCmd)
+	applyCmd.Flags().StringVar(&applyCompression, "compression", "", "Store the version as a compressed archive: none, gzip or zstd (default from config.json)")
 	rootCmd.AddCommand(applyCmd)
 }
@@ -0,0 +1,80 @@
+package cloudtm
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/raxkumar/cloudtm/helper"
+	"github.com/spf13/cobra"
+)
+
+// annotationTerraform marks commands that run Terraform, so the audit log
+// records the Terraform version they used
+const annotationTerraform = "cloudtm/terraform"
+
+// auditEntry is the audit log entry of the running command, written when it exits
+var auditEntry *helper.AuditEntry
+
+// runningCmd is the command being executed, for entries finished by exit
+var runningCmd *cobra.Command
+
+// startAudit begins the audit log entry of a command (root PersistentPreRun)
+func startAudit(cmd *cobra.Command, args []string) {
+	if cmd == logCmd || cmd.Name() == "help" {
+		return
+	}
+	runningCmd = cmd
+	auditEntry = &helper.AuditEntry{
+		Command: strings.TrimPrefix(cmd.CommandPath(), cmd.Root().Name()+" "),
+		Args:    helper.RedactVarValues(os.Args[1:]),
+		User:    helper.CurrentUser(),
+		Start:   time.Now().UTC().Format(time.RFC3339Nano),
+	}
+	auditEntry.Host, _ = os.Hostname()
+}
+
+// finishAudit completes the audit log entry with the exit status and appends
+// it to .cloudtm/audit.log. Nothing is written outside an initialized project.
+func finishAudit(cmd *cobra.Command, status int) {
+	entry := auditEntry
+	auditEntry = nil
+	if entry == nil {
+		return
+	}
+
+	cwd, _ := os.Getwd()
+	cloudtmDir := filepath.Join(cwd, ".cloudtm")
+	if _, err := os.Stat(cloudtmDir); err != nil {
+		return
+	}
+
+	entry.End = time.Now().UTC().Format(time.RFC3339Nano)
+	entry.ExitStatus = status
+	entry.GitCommit = helper.GitCommit(cwd)
+	if cmd != nil && cmd.Annotations[annotationTerraform] != "" {
+		binary := activeBinary
+		if binary == nil {
+			binary, _, _ = detectBinary()
+		}
+		if binary != nil {
+			entry.TerraformVersion = binary.Version
+			entry.TerraformBinary = binary.Name
+		}
+	}
+	entry.Version, _, _ = helper.GetCurrentVersion(cloudtmDir)
+
+	if err := helper.AppendAudit(cloudtmDir, entry); err != nil {
+		fmt.Println("⚠️  Warning: Failed to write audit log:", err)
+	}
+}
+
+// exit records the audit log entry of the running command, releases its
+// lock and exits with code
+func exit(code int) {
+	finishAudit(runningCmd, code)
+	releaseLock()
+	os.Exit(code)
+}
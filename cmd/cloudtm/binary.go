@@ -0,0 +1,90 @@
+package cloudtm
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/raxkumar/cloudtm/helper"
+)
+
+var tfBinaryFlag string
+
+// activeBinary is the Terraform-compatible binary of the running command,
+// set by requireBinary
+var activeBinary *helper.Binary
+
+// binarySetting returns the configured binary and where it was configured:
+// --tf-binary, CLOUDTM_TF_BINARY or tfBinary in config.json. An empty
+// setting means terraform, or tofu when terraform is not installed.
+func binarySetting() (string, string) {
+	if tfBinaryFlag != "" {
+		return tfBinaryFlag, "--tf-binary"
+	}
+	if env := os.Getenv("CLOUDTM_TF_BINARY"); env != "" {
+		return env, "CLOUDTM_TF_BINARY"
+	}
+	cwd, _ := os.Getwd()
+	if cfg, err := helper.LoadConfig(filepath.Join(cwd, ".cloudtm")); err == nil && cfg.TFBinary != "" {
+		return cfg.TFBinary, "config.json"
+	}
+	return "", ""
+}
+
+// detectBinary resolves the configured binary and detects its version
+func detectBinary() (*helper.Binary, string, error) {
+	setting, source := binarySetting()
+	path, err := helper.FindBinary(setting)
+	if err != nil {
+		return nil, source, err
+	}
+	binary, err := helper.DetectBinary(path)
+	return binary, source, err
+}
+
+// requireBinary selects the Terraform-compatible binary for the running
+// command, exiting when it is missing or too old for cloudtm
+func requireBinary() *helper.Binary {
+	if activeBinary != nil {
+		return activeBinary
+	}
+
+	binary, source, err := detectBinary()
+	if err != nil {
+		fmt.Println("❌ Error:", err)
+		if source != "" {
+			fmt.Printf("💡 The binary is set by %s\n", source)
+		} else {
+			fmt.Println("Please install Terraform: https://developer.hashicorp.com/terraform/downloads")
+			fmt.Println("or OpenTofu: https://opentofu.org/docs/intro/install/")
+		}
+		exit(1)
+	}
+	if !binary.SupportsJSONUI() {
+		fmt.Printf("❌ %s does not support -json output; cloudtm requires Terraform 0.15.3 or later, or OpenTofu\n", binary)
+		exit(1)
+	}
+	if source != "" || binary.Name != helper.BinaryTerraform {
+		fmt.Printf("🔧 Using %s (%s)\n", binary, binary.Path)
+	}
+
+	helper.UseBinary(binary.Path)
+	activeBinary = binary
+	return binary
+}
+
+// warnBinaryChange warns when a version was created with a different
+// Terraform-compatible binary or version than the one running now
+func warnBinaryChange(meta *helper.Metadata) {
+	if meta == nil || meta.Binary == nil || activeBinary == nil {
+		return
+	}
+	if activeBinary.Differs(meta.Binary) {
+		fmt.Printf("⚠️  Warning: %s was created with %s, now running %s\n", meta.Version, meta.Binary, activeBinary)
+		fmt.Println("💡 Provider and state format differences may cause unexpected changes; use --tf-binary to pick another binary")
+	}
+}
+
+func init() {
+	rootCmd.PersistentFlags().StringVar(&tfBinaryFlag, "tf-binary", "", "Terraform-compatible binary to run, e.g. tofu or /opt/terraform-1.5.7 (default terraform, else tofu)")
+}
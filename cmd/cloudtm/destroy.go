@@ -3,7 +3,6 @@ package cloudtm
 import (
 	"fmt"
 	"os"
-	"os/exec"
 	"path/filepath"
 
 	"github.com/raxkumar/cloudtm/helper"
@@ -11,6 +10,7 @@ import (
 )
 
 var autoApproveDestroy bool
+var destroyTF terraformFlags
 
 var destroyCmd = &cobra.Command{
 	Use:   "destroy",
@@ -18,14 +18,20 @@ var destroyCmd = &cobra.Command{
 	Long: `Destroys Terraform infrastructure resources.
 Behaviors:
 - 'cloudtm destroy' runs interactively like Terraform (requires user confirmation).
-- 'cloudtm destroy --auto-approve' skips manual approval automatically.`,
+- 'cloudtm destroy --auto-approve' skips manual approval automatically.
+
+Variables, targets and other options are forwarded to Terraform: use the flags
+below or pass any Terraform flag after "--".
+
+Usage:
+    cloudtm destroy --var-file prod.tfvars
+    cloudtm destroy --target aws_instance.web
+    cloudtm destroy -- -lock-timeout=5m`,
+	Args:        terraformPassthroughArgs,
+	Annotations: map[string]string{annotationTerraform: "true", annotationMutating: "true"},
 	Run: func(cmd *cobra.Command, args []string) {
-		// Step 1: Ensure Terraform exists
-		if _, err := exec.LookPath("terraform"); err != nil {
-			fmt.Println("❌ Terraform not found in PATH.")
-			fmt.Println("Please install Terraform: https://developer.hashicorp.com/terraform/downloads")
-			os.Exit(1)
-		}
+		// Step 1: Select the Terraform-compatible binary
+		requireBinary()
 
 		// Step 2: Verify CloudTimeMachine is initialized
 		cwd, _ := os.Getwd()
@@ -33,11 +39,11 @@ Behaviors:
 
 		if _, err := os.Stat(cloudtmDir); os.IsNotExist(err) {
 			fmt.Println("❌ CloudTimeMachine not initialized. Run: cloudtm init")
-			os.Exit(1)
+			exit(1)
 		}
 
 		// Step 3: Build Terraform command
-		tfArgs := []string{"destroy"}
+		tfArgs := append([]string{"destroy"}, destroyTF.args(cmd, args)...)
 		if autoApproveDestroy {
 			tfArgs = append(tfArgs, "--auto-approve")
 			fmt.Println("🚀 Running 'terraform destroy --auto-approve'...")
@@ -45,7 +51,11 @@ Behaviors:
 			fmt.Println("🚀 Running 'terraform destroy' (interactive)...")
 		}
 
-		tfCmd := exec.Command("terraform", tfArgs...)
+		// Count managed resources before and after, since an interactive
+		// destroy cannot report them as -json events
+		before := managedResourceCount(cwd)
+
+		tfCmd := helper.TerraformCommand(cwd, tfArgs...)
 
 		// Step 4: Stream output to user
 		tfCmd.Stdout = os.Stdout
@@ -55,11 +65,20 @@ Behaviors:
 		// Step 5: Run Terraform
 		if err := tfCmd.Run(); err != nil {
 			fmt.Println("\n❌ Terraform destroy failed:", err)
-			os.Exit(1)
+			exit(1)
 		}
 
 		fmt.Println("\n✅ Terraform destroy completed successfully.")
 
+		event := helper.NewHistoryEvent(helper.EventDestroy)
+		event.Version, _, _ = helper.GetCurrentVersion(cloudtmDir)
+		if before >= 0 {
+			if after := managedResourceCount(cwd); after >= 0 && after <= before {
+				event.Resources = &helper.ResourceCounts{Destroyed: before - after}
+			}
+		}
+		recordEvent(cloudtmDir, event)
+
 		// Update status to false after successful destroy
 		if err := helper.SetCurrentStatus(cloudtmDir, false); err != nil {
 			fmt.Println("⚠️  Warning: Failed to update current status:", err)
@@ -67,8 +86,19 @@ Behaviors:
 	},
 }
 
+// managedResourceCount returns the number of managed resource instances in
+// the project's local state, or -1 when it cannot be read
+func managedResourceCount(cwd string) int {
+	state, err := helper.ReadState(filepath.Join(cwd, helper.StateFileName))
+	if err != nil {
+		return -1
+	}
+	return len(state.ManagedInstances())
+}
+
 func init() {
 	destroyCmd.Flags().BoolVar(&autoApproveDestroy, "auto-approve", false, "Skip interactive approval")
+	destroyTF.register(destroyCmd)
 	rootCmd.AddCommand(destroyCmd)
 }
 
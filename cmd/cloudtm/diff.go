@@ -0,0 +1,177 @@
+package cloudtm
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/raxkumar/cloudtm/helper"
+	"github.com/spf13/cobra"
+)
+
+var diffState bool
+
+var diffCmd = &cobra.Command{
+	Use:   "diff <a> [b]",
+	Short: "show changes between two versions",
+	Long: `Shows what changed between two versions. Each side is a version, a tag,
+or "." for the working directory; with a single argument the version is
+compared with the working directory.
+
+By default a unified diff of the configuration files is shown. With --state
+the Terraform states are compared by resource address instead, listing
+resources added, removed or changed, the attributes that changed and
+changed outputs.
+Sensitive attribute values are never shown.
+
+Usage:
+    cloudtm diff v4 v9               # Config changes from v4 to v9
+    cloudtm diff pre-migration .     # Config changes since a tagged version
+    cloudtm diff v4                  # Same as: cloudtm diff v4 .
+    cloudtm diff v4 v9 --state       # Resource and attribute changes`,
+	Args: cobra.RangeArgs(1, 2),
+	Run: func(cmd *cobra.Command, args []string) {
+		cwd, _ := os.Getwd()
+		cloudtmDir := filepath.Join(cwd, ".cloudtm")
+
+		if _, err := os.Stat(cloudtmDir); os.IsNotExist(err) {
+			fmt.Println("❌ CloudTimeMachine not initialized. Run: cloudtm init")
+			exit(1)
+		}
+		if len(args) == 1 {
+			args = append(args, ".")
+		}
+
+		store := localStore(cloudtmDir)
+		aLabel, aFiles := loadDiffSide(store, cwd, args[0])
+		bLabel, bFiles := loadDiffSide(store, cwd, args[1])
+
+		if diffState {
+			showStateDiff(aLabel, bLabel, aFiles, bFiles)
+			return
+		}
+
+		diff := helper.DiffFileSets(aLabel, bLabel, aFiles, bFiles, helper.IsStateFile)
+		if diff == "" {
+			fmt.Printf("✅ No configuration changes between %s and %s\n", aLabel, bLabel)
+			return
+		}
+		fmt.Print(diff)
+	},
+}
+
+// loadDiffSide reads the files of a version, tag or the working directory (".")
+func loadDiffSide(store helper.SnapshotStore, cwd, ref string) (string, map[string][]byte) {
+	if ref == "." {
+		files, err := helper.LoadProjectFiles(cwd)
+		if err != nil {
+			fmt.Println("❌ Error reading working directory:", err)
+			exit(1)
+		}
+		return "working", files
+	}
+
+	version := resolveVersion(store, ref)
+	files, err := helper.LoadVersionFiles(store, version)
+	if err != nil {
+		fmt.Printf("❌ Error reading version '%s': %v\n", version, err)
+		exit(1)
+	}
+	return version, files
+}
+
+// showStateDiff prints the resource-level differences between the states of two sides
+func showStateDiff(aLabel, bLabel string, aFiles, bFiles map[string][]byte) {
+	var stateFiles []string
+	for path := range aFiles {
+		if helper.IsStateFile(path) && filepath.Ext(path) == ".tfstate" {
+			stateFiles = append(stateFiles, path)
+		}
+	}
+	for path := range bFiles {
+		if _, ok := aFiles[path]; !ok && helper.IsStateFile(path) && filepath.Ext(path) == ".tfstate" {
+			stateFiles = append(stateFiles, path)
+		}
+	}
+	sort.Strings(stateFiles)
+	if len(stateFiles) == 0 {
+		fmt.Printf("ℹ️  Neither %s nor %s contains a terraform.tfstate\n", aLabel, bLabel)
+		return
+	}
+
+	fmt.Printf("📊 State changes from %s to %s\n", aLabel, bLabel)
+	fmt.Println("──────────────────────────────────────────────────────────────")
+	added, removed, changed, outputs := 0, 0, 0, 0
+	for _, path := range stateFiles {
+		diff, err := helper.DiffStates(aFiles[path], bFiles[path])
+		if err != nil {
+			fmt.Printf("❌ %s: %v\n", path, err)
+			exit(1)
+		}
+		if len(stateFiles) > 1 && !diff.IsEmpty() {
+			fmt.Printf("%s:\n", path)
+		}
+
+		for _, d := range diff.Resources {
+			switch d.Action {
+			case "added":
+				fmt.Printf("  + %s\n", d.Address)
+				added++
+			case "removed":
+				fmt.Printf("  - %s\n", d.Address)
+				removed++
+			case "changed":
+				fmt.Printf("  ~ %s\n", d.Address)
+				for _, attr := range d.Attributes {
+					fmt.Printf("      %s\n", formatAttributeDiff(attr))
+				}
+				changed++
+			}
+		}
+		if len(diff.Outputs) > 0 {
+			fmt.Println("  Outputs:")
+			for _, out := range diff.Outputs {
+				fmt.Printf("      %s\n", formatAttributeDiff(out))
+			}
+			outputs += len(diff.Outputs)
+		}
+	}
+
+	fmt.Println("──────────────────────────────────────────────────────────────")
+	if added+removed+changed+outputs == 0 {
+		fmt.Println("✅ No resource or output changes")
+		return
+	}
+	fmt.Printf("Resources: %d added, %d changed, %d removed; outputs: %d changed\n", added, changed, removed, outputs)
+}
+
+func formatAttributeDiff(d helper.AttributeDiff) string {
+	oldValue, newValue := formatValue(d.Old), formatValue(d.New)
+	if d.Sensitive {
+		oldValue, newValue = "(sensitive value)", "(sensitive value)"
+	}
+	switch {
+	case !d.HasOld:
+		return fmt.Sprintf("+ %s: %s", d.Path, newValue)
+	case !d.HasNew:
+		return fmt.Sprintf("- %s: %s", d.Path, oldValue)
+	case d.Sensitive:
+		return fmt.Sprintf("~ %s: %s", d.Path, newValue)
+	}
+	return fmt.Sprintf("~ %s: %s → %s", d.Path, oldValue, newValue)
+}
+
+func formatValue(v interface{}) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
+
+func init() {
+	diffCmd.Flags().BoolVar(&diffState, "state", false, "Compare Terraform state by resource instead of configuration files")
+	rootCmd.AddCommand(diffCmd)
+}
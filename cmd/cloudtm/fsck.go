@@ -0,0 +1,123 @@
+package cloudtm
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/raxkumar/cloudtm/helper"
+	"github.com/spf13/cobra"
+)
+
+var fsckRemote string
+
+var fsckCmd = &cobra.Command{
+	Use:   "fsck",
+	Short: "verify the integrity of stored snapshots",
+	Long: `Checks every version in the snapshot store:
+- each version has metadata and snapshot files
+- every object referenced by a version's manifest exists
+- every object's content matches its SHA-256 hash
+- compressed version archives can be fully read
+
+Usage:
+    cloudtm fsck                     # Check the local .cloudtm directory
+    cloudtm fsck --remote s3://bucket/app`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		// Step 1: Verify CloudTimeMachine is initialized
+		cwd, _ := os.Getwd()
+		cloudtmDir := filepath.Join(cwd, ".cloudtm")
+
+		if _, err := os.Stat(cloudtmDir); os.IsNotExist(err) {
+			fmt.Println("❌ CloudTimeMachine not initialized. Run: cloudtm init")
+			exit(1)
+		}
+
+		store := localStore(cloudtmDir)
+		if fsckRemote != "" {
+			store = openRemote(cloudtmDir, fsckRemote)
+		}
+		fmt.Printf("🔍 Checking snapshots in %s...\n", store)
+
+		// Step 2: Collect versions known from metadata and from snapshot files
+		metaVersions, err := helper.StoreVersions(store)
+		if err != nil {
+			fmt.Println("❌ Error listing metadata:", err)
+			exit(1)
+		}
+		snapshotVersions, err := helper.SnapshotVersions(store)
+		if err != nil {
+			fmt.Println("❌ Error listing versions:", err)
+			exit(1)
+		}
+
+		hasMeta := make(map[string]bool)
+		all := make(map[string]bool)
+		for _, v := range metaVersions {
+			hasMeta[v] = true
+			all[v] = true
+		}
+		for _, v := range snapshotVersions {
+			all[v] = true
+		}
+
+		var versions []string
+		for v := range all {
+			versions = append(versions, v)
+		}
+		sort.Slice(versions, func(i, j int) bool {
+			return helper.VersionNumber(versions[i]) < helper.VersionNumber(versions[j])
+		})
+
+		// Step 3: Check each version
+		problems := 0
+		for _, v := range versions {
+			if !hasMeta[v] {
+				fmt.Printf("❌ %s: missing metadata (meta/%s.json)\n", v, v)
+				problems++
+			}
+
+			exists, err := helper.VersionExists(store, v)
+			if err != nil {
+				fmt.Printf("❌ %s: %v\n", v, err)
+				problems++
+				continue
+			}
+			if !exists {
+				fmt.Printf("❌ %s: missing snapshot files\n", v)
+				problems++
+				continue
+			}
+
+			format, checked, objectProblems, err := helper.CheckVersion(store, v)
+			if err != nil {
+				fmt.Printf("❌ %s: %v\n", v, err)
+				problems++
+				continue
+			}
+			for _, p := range objectProblems {
+				fmt.Printf("❌ %s\n", p.Error())
+			}
+			problems += len(objectProblems)
+
+			if len(objectProblems) == 0 {
+				fmt.Printf("✅ %s: %d files ok (%s)\n", v, checked, format)
+			}
+		}
+
+		// Step 4: Summary
+		fmt.Println("──────────────────────────────────────────────────────────────")
+		if problems > 0 {
+			fmt.Printf("❌ %d problem(s) found in %d version(s)\n", problems, len(versions))
+			exit(1)
+		}
+		fmt.Printf("✅ All %d version(s) verified\n", len(versions))
+	},
+}
+
+func init() {
+	fsckCmd.Flags().StringVar(&fsckRemote, "remote", "", "Check a remote store instead of the local .cloudtm directory")
+	rootCmd.AddCommand(fsckCmd)
+}
@@ -4,9 +4,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
-	"os/exec"
 	"path/filepath"
 
+	"github.com/raxkumar/cloudtm/helper"
 	"github.com/spf13/cobra"
 )
 
@@ -17,15 +17,12 @@ var initCmd = &cobra.Command{
 This command:
 1. Checks for Terraform installation.
 2. Creates the .cloudtm/ directory with versions/ and meta/ subfolders.
-3. Runs 'terraform init' as a wrapper.`,
+3. Upgrades existing metadata files to the current schema.
+4. Runs 'terraform init' as a wrapper.`,
+	Annotations: map[string]string{annotationTerraform: "true", annotationMutating: "true"},
 	Run: func(cmd *cobra.Command, args []string) {
-		// Step 1: Check if terraform is installed
-		_, err := exec.LookPath("terraform")
-		if err != nil {
-			fmt.Println("❌ Terraform not found in PATH.")
-			fmt.Println("Please install Terraform: https://developer.hashicorp.com/terraform/downloads")
-			os.Exit(1)
-		}
+		// Step 1: Select the Terraform-compatible binary
+		requireBinary()
 
 		// Step 2: Create .cloudtm/ folder structure
 		cwd, _ := os.Getwd()
@@ -38,11 +35,11 @@ This command:
 		if _, err := os.Stat(cloudtmDir); os.IsNotExist(err) {
 			if err := os.MkdirAll(versionsDir, 0755); err != nil {
 				fmt.Println("Error creating versions directory:", err)
-				os.Exit(1)
+				exit(1)
 			}
 			if err := os.MkdirAll(metaDir, 0755); err != nil {
 				fmt.Println("Error creating meta directory:", err)
-				os.Exit(1)
+				exit(1)
 			}
 			fmt.Println("✅ Created .cloudtm/ directory with versions/ and meta/ folders.")
 		} else {
@@ -59,9 +56,9 @@ This command:
 				"status":  false,
 			}
 			currentJSON, _ := json.MarshalIndent(currentData, "", "  ")
-			if err := os.WriteFile(currentFile, currentJSON, 0644); err != nil {
+			if err := helper.WriteFileAtomic(currentFile, currentJSON, 0644); err != nil {
 				fmt.Println("Error creating current.json file:", err)
-				os.Exit(1)
+				exit(1)
 			}
 			fmt.Println("✅ Created 'current.json' file to track snapshot versions.")
 		}
@@ -72,17 +69,29 @@ This command:
 				"rollback": "",
 			}
 			rollbackJSON, _ := json.MarshalIndent(rollbackData, "", "  ")
-			if err := os.WriteFile(rollbackFile, rollbackJSON, 0644); err != nil {
+			if err := helper.WriteFileAtomic(rollbackFile, rollbackJSON, 0644); err != nil {
 				fmt.Println("Error creating rollback.json file:", err)
-				os.Exit(1)
+				exit(1)
 			}
 			fmt.Println("✅ Created 'rollback.json' file to track rollback status.")
 		}
 
+		// Upgrade metadata written by older cloudtm releases
+		migrated, badMeta, err := helper.MigrateMetadata(cloudtmDir)
+		if err != nil {
+			fmt.Println("⚠️  Warning: Could not migrate metadata:", err)
+		}
+		if len(migrated) > 0 {
+			fmt.Printf("✅ Migrated %d metadata file(s) to schema version %d.\n", len(migrated), helper.MetadataSchemaVersion)
+		}
+		for _, e := range badMeta {
+			fmt.Printf("⚠️  Could not migrate meta/%s\n", e.Error())
+		}
+
 		// Step 3: Run terraform init
 		fmt.Println("\n🚀 Running 'terraform init'...")
 
-		tfCmd := exec.Command("terraform", append([]string{"init"}, args...)...)
+		tfCmd := helper.TerraformCommand(cwd, append([]string{"init"}, args...)...)
 		tfCmd.Stdout = os.Stdout
 		tfCmd.Stderr = os.Stderr
 		tfCmd.Stdin = os.Stdin
@@ -90,7 +99,7 @@ This command:
 		err = tfCmd.Run()
 		if err != nil {
 			fmt.Println("\n❌ Terraform initialization failed:", err)
-			os.Exit(1)
+			exit(1)
 		}
 
 		fmt.Println("\n✅ Terraform initialized successfully.")
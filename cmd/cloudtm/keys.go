@@ -0,0 +1,254 @@
+package cloudtm
+
+import (
+	"crypto/ed25519"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/raxkumar/cloudtm/helper"
+	"github.com/spf13/cobra"
+)
+
+var keygenForce bool
+var keygenSigning bool
+
+var keygenCmd = &cobra.Command{
+	Use:   "keygen <file>",
+	Short: "generate a snapshot encryption or signing key",
+	Long: `Generates a random 256-bit key for client-side snapshot encryption and
+writes it (base64 encoded) to a file readable only by the owner.
+
+Snapshot content (configs, terraform.tfstate, plans) is encrypted with
+AES-256-GCM before it is written to .cloudtm or a remote store. Metadata
+(timestamps, change counts) stays readable so 'cloudtm list' works without
+the key.
+
+The key is read from, in order of precedence:
+    CLOUDTM_ENCRYPTION_KEY           # the base64 or hex encoded key itself
+    CLOUDTM_ENCRYPTION_KEY_FILE      # path to a key file
+    encryptionKeyFile                # in .cloudtm/config.json
+
+Keep the key outside the project and back it up: versions encrypted with a
+lost key cannot be restored.
+
+With --signing an ed25519 key pair is generated instead: the private key is
+written to <file> and the public key to <file>.pub. Set "signingKeyFile" in
+.cloudtm/config.json (or CLOUDTM_SIGNING_KEY_FILE) to sign the hash chain
+link of every new version; 'cloudtm verify --public-key <file>.pub' checks
+the signatures.
+
+Usage:
+    cloudtm keygen ~/.cloudtm/app.key
+    cloudtm rekey --new-key-file ~/.cloudtm/app.key
+    cloudtm keygen --signing ~/.cloudtm/sign.key`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		path := args[0]
+		if _, err := os.Stat(path); err == nil && !keygenForce {
+			fmt.Printf("❌ %s already exists. Use --force to overwrite it.\n", path)
+			exit(1)
+		}
+
+		if keygenSigning {
+			writeSigningKey(path)
+			return
+		}
+
+		key, err := helper.GenerateKey()
+		if err != nil {
+			fmt.Println("❌ Error generating key:", err)
+			exit(1)
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			fmt.Println("❌ Error creating key directory:", err)
+			exit(1)
+		}
+		if err := os.WriteFile(path, []byte(helper.EncodeKey(key)+"\n"), 0600); err != nil {
+			fmt.Println("❌ Error writing key file:", err)
+			exit(1)
+		}
+
+		fmt.Printf("🔑 Encryption key written to %s\n", path)
+		fmt.Println("ℹ️  Enable it with: cloudtm rekey --new-key-file", path)
+	},
+}
+
+// writeSigningKey generates an ed25519 key pair and writes it to path and path.pub
+func writeSigningKey(path string) {
+	public, private, err := helper.GenerateSigningKey()
+	if err != nil {
+		fmt.Println("❌ Error generating key:", err)
+		exit(1)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		fmt.Println("❌ Error creating key directory:", err)
+		exit(1)
+	}
+	if err := os.WriteFile(path, []byte(helper.EncodeSigningKey(private)+"\n"), 0600); err != nil {
+		fmt.Println("❌ Error writing key file:", err)
+		exit(1)
+	}
+	if err := os.WriteFile(path+".pub", []byte(helper.EncodePublicKey(public)+"\n"), 0644); err != nil {
+		fmt.Println("❌ Error writing public key file:", err)
+		exit(1)
+	}
+
+	fmt.Printf("🔑 Signing key written to %s (key id %s)\n", path, helper.PublicKeyID(public))
+	fmt.Printf("🔓 Public key written to %s.pub\n", path)
+	fmt.Printf("ℹ️  Enable it by setting \"signingKeyFile\": %q in .cloudtm/config.json\n", path)
+}
+
+var (
+	rekeyNewKeyFile string
+	rekeyOldKeyFile string
+	rekeyDecrypt    bool
+)
+
+var rekeyCmd = &cobra.Command{
+	Use:   "rekey",
+	Short: "encrypt, re-encrypt or decrypt all stored versions",
+	Long: `Rewrites the snapshot content of every local version with a new encryption key.
+Content encrypted with the current key is decrypted first and unencrypted
+content is encrypted, so rekey also enables encryption on an existing project.
+
+The current key is the configured one (see 'cloudtm keygen') unless
+--old-key-file is given. Afterwards encryptionKeyFile in config.json points
+to the new key file (or is removed with --decrypt). Decrypting redacts the
+sensitive variable values stored in each version's variables.json and
+plan.json; the binary plan.tfplan files cannot be redacted and keep them.
+
+Remote stores are not rewritten; push again after rekeying or run rekey in a
+clone of the remote.
+
+Usage:
+    cloudtm rekey --new-key-file ~/.cloudtm/new.key
+    cloudtm rekey --old-key-file old.key --new-key-file new.key
+    cloudtm rekey --decrypt          # Store versions unencrypted again`,
+	Args:        cobra.NoArgs,
+	Annotations: map[string]string{annotationMutating: "true"},
+	Run: func(cmd *cobra.Command, args []string) {
+		cwd, _ := os.Getwd()
+		cloudtmDir := filepath.Join(cwd, ".cloudtm")
+
+		if _, err := os.Stat(cloudtmDir); os.IsNotExist(err) {
+			fmt.Println("❌ CloudTimeMachine not initialized. Run: cloudtm init")
+			exit(1)
+		}
+		if rekeyDecrypt == (rekeyNewKeyFile != "") {
+			fmt.Println("❌ Specify exactly one of --new-key-file or --decrypt")
+			exit(1)
+		}
+
+		cfg, err := helper.LoadConfig(cloudtmDir)
+		if err != nil {
+			fmt.Println("❌ Error reading config.json:", err)
+			exit(1)
+		}
+
+		// Step 1: Load the current and the new key
+		var oldKey []byte
+		if rekeyOldKeyFile != "" {
+			oldKey, err = helper.ReadKeyFile(rekeyOldKeyFile)
+		} else {
+			oldKey, err = helper.LoadEncryptionKey(cfg)
+		}
+		if err != nil {
+			fmt.Println("❌ Error reading current encryption key:", err)
+			exit(1)
+		}
+
+		var newKey []byte
+		if rekeyNewKeyFile != "" {
+			if newKey, err = helper.ReadKeyFile(rekeyNewKeyFile); err != nil {
+				fmt.Println("❌ Error reading new encryption key:", err)
+				exit(1)
+			}
+		}
+
+		// Step 2: Rewrite every object of the raw local store
+		fmt.Printf("🔐 Rewriting snapshot content in %s...\n", cloudtmDir)
+		rewritten, err := helper.Rekey(helper.LocalStore(cloudtmDir), oldKey, newKey)
+		if err != nil {
+			fmt.Printf("❌ Rekey failed after %d object(s): %v\n", rewritten, err)
+			fmt.Println("ℹ️  Rerun the same command to finish; objects already rewritten are skipped.")
+			exit(1)
+		}
+
+		// Step 3: Point config.json at the new key
+		cfg.EncryptionKeyFile = rekeyNewKeyFile
+		if err := helper.SaveConfig(cloudtmDir, cfg); err != nil {
+			fmt.Println("❌ Error writing config.json:", err)
+			exit(1)
+		}
+
+		if newKey == nil {
+			fmt.Printf("✅ Decrypted %d object(s); new versions are stored unencrypted\n", rewritten)
+			fmt.Printf("⚠️  Warning: %s files cannot be redacted and still hold sensitive variable values in plaintext\n", helper.PlanFileName)
+		} else {
+			fmt.Printf("✅ Encrypted %d object(s) with the new key\n", rewritten)
+		}
+		if os.Getenv("CLOUDTM_ENCRYPTION_KEY") != "" || os.Getenv("CLOUDTM_ENCRYPTION_KEY_FILE") != "" {
+			fmt.Println("⚠️  CLOUDTM_ENCRYPTION_KEY(_FILE) is set in the environment and overrides config.json — update it")
+		}
+	},
+}
+
+// encryptionKey returns the project's snapshot encryption key, or nil when
+// encryption is not enabled
+func encryptionKey(cloudtmDir string) []byte {
+	cfg, err := helper.LoadConfig(cloudtmDir)
+	if err != nil {
+		fmt.Println("❌ Error reading config.json:", err)
+		exit(1)
+	}
+	key, err := helper.LoadEncryptionKey(cfg)
+	if err != nil {
+		fmt.Println("❌ Error reading encryption key:", err)
+		exit(1)
+	}
+	return key
+}
+
+// signingKey returns the project's version signing key, or nil when signing
+// is not enabled
+func signingKey(cloudtmDir string) ed25519.PrivateKey {
+	cfg, err := helper.LoadConfig(cloudtmDir)
+	if err != nil {
+		fmt.Println("❌ Error reading config.json:", err)
+		exit(1)
+	}
+	key, err := helper.LoadSigningKey(cfg)
+	if err != nil {
+		fmt.Println("❌ Error reading signing key:", err)
+		exit(1)
+	}
+	return key
+}
+
+// sealVersion links the metadata of a newly stored version into the hash
+// chain (signed when a signing key is configured), warning on failure
+func sealVersion(store helper.SnapshotStore, cloudtmDir string, meta *helper.Metadata) {
+	metas, _, err := helper.LoadAllMetadata(cloudtmDir)
+	if err == nil {
+		err = helper.SealVersion(store, meta, metas, signingKey(cloudtmDir))
+	}
+	if err != nil {
+		fmt.Println("⚠️  Warning: Failed to add version to the hash chain:", err)
+	}
+}
+
+// localStore opens the project's .cloudtm store with the configured encryption
+func localStore(cloudtmDir string) helper.SnapshotStore {
+	return helper.WithEncryption(helper.LocalStore(cloudtmDir), encryptionKey(cloudtmDir))
+}
+
+func init() {
+	keygenCmd.Flags().BoolVar(&keygenForce, "force", false, "Overwrite an existing key file")
+	keygenCmd.Flags().BoolVar(&keygenSigning, "signing", false, "Generate an ed25519 key pair for signing versions instead")
+	rekeyCmd.Flags().StringVar(&rekeyNewKeyFile, "new-key-file", "", "Key file to encrypt all versions with")
+	rekeyCmd.Flags().StringVar(&rekeyOldKeyFile, "old-key-file", "", "Key file the versions are currently encrypted with (defaults to the configured key)")
+	rekeyCmd.Flags().BoolVar(&rekeyDecrypt, "decrypt", false, "Decrypt all versions and disable encryption")
+	rootCmd.AddCommand(keygenCmd)
+	rootCmd.AddCommand(rekeyCmd)
+}
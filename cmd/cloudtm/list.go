@@ -1,12 +1,10 @@
 package cloudtm
 
 import (
-	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
 	"sort"
-	"strconv"
 	"strings"
 	"text/tabwriter"
 
@@ -14,28 +12,27 @@ import (
 	"github.com/spf13/cobra"
 )
 
-type VersionMetadata struct {
-	Version   string
-	Timestamp string
-	Added     string
-	Changed   string
-	Destroyed string
-}
+var listChanges bool
+var listTimeline bool
 
 var listCmd = &cobra.Command{
-	Use:   "list",
+	Use:   "list [versions|tags...]",
 	Short: "list available state snapshots and versions",
 	Long: `Lists all available CloudTimeMachine snapshot versions with metadata.
-Shows version number, timestamp, and resource change statistics for each snapshot.`,
+Shows version number, timestamp, and resource change statistics for each snapshot.
+Use --changes to also show the resource-level changes recorded in each version's plan.
+Pass versions or tags to list only those versions.
+Use --timeline to show every recorded event instead: applies, snapshots,
+destroys, rollbacks (applied, in-place, promoted and destroyed) and versions
+deleted by prune.`,
 	Run: func(cmd *cobra.Command, args []string) {
 		// Step 1: Check CloudTM is initialized
 		cwd, _ := os.Getwd()
 		cloudtmDir := filepath.Join(cwd, ".cloudtm")
-		metaDir := filepath.Join(cloudtmDir, "meta")
 
 		if _, err := os.Stat(cloudtmDir); os.IsNotExist(err) {
 			fmt.Println("❌ CloudTimeMachine not initialized. Run: cloudtm init")
-			os.Exit(1)
+			exit(1)
 		}
 
 		// Step 2: Get current version and status
@@ -46,53 +43,34 @@ Shows version number, timestamp, and resource change statistics for each snapsho
 			currentStatus = false
 		}
 
-		// Step 3: Read all metadata files
-		files, err := os.ReadDir(metaDir)
+		// Step 3: Read all metadata files, collecting malformed ones instead of failing
+		versions, badMeta, err := helper.LoadAllMetadata(cloudtmDir)
 		if err != nil {
 			fmt.Println("❌ Error reading meta directory:", err)
-			os.Exit(1)
+			exit(1)
 		}
 
-		// Filter and collect version metadata
-		var versions []VersionMetadata
-		for _, file := range files {
-			if file.IsDir() || !strings.HasSuffix(file.Name(), ".json") {
-				continue
-			}
-
-			metaPath := filepath.Join(metaDir, file.Name())
-			data, err := os.ReadFile(metaPath)
-			if err != nil {
-				continue
-			}
-
-			var meta map[string]interface{}
-			if err := json.Unmarshal(data, &meta); err != nil {
-				continue
-			}
-
-			resources, _ := meta["resources"].(map[string]interface{})
-			version := VersionMetadata{
-				Version:   meta["version"].(string),
-				Timestamp: meta["timestamp"].(string),
-				Added:     resources["added"].(string),
-				Changed:   resources["changed"].(string),
-				Destroyed: resources["destroyed"].(string),
-			}
-			versions = append(versions, version)
+		if listTimeline {
+			showTimeline(cloudtmDir, versions)
+			reportMalformedMetadata(badMeta)
+			return
 		}
 
 		// Step 4: Check if any versions exist
 		if len(versions) == 0 {
 			fmt.Println("ℹ️  No versions found. Run 'cloudtm apply' to create your first snapshot.")
+			reportMalformedMetadata(badMeta)
 			return
 		}
 
-		// Step 5: Sort versions (v1, v2, v3... in descending order for display)
+		// Only show the requested versions
+		if len(args) > 0 {
+			versions = filterVersions(cloudtmDir, versions, args)
+		}
+
+		// Step 5: Sort versions in descending order for display
 		sort.Slice(versions, func(i, j int) bool {
-			numI := extractVersionNumber(versions[i].Version)
-			numJ := extractVersionNumber(versions[j].Version)
-			return numI > numJ
+			return helper.VersionNumber(versions[i].Version) > helper.VersionNumber(versions[j].Version)
 		})
 
 		// Step 6: Display header
@@ -107,13 +85,13 @@ Shows version number, timestamp, and resource change statistics for each snapsho
 			}
 			fmt.Printf("Current: %s (%s)\n\n", currentVersion, statusText)
 		} else {
-			fmt.Println("Current: None\n")
+			fmt.Print("Current: None\n\n")
 		}
 
 		// Step 7: Create table with tabwriter
 		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
-		fmt.Fprintln(w, "Version\tTimestamp\tAdded\tChanged\tDestroyed\tStatus")
-		fmt.Fprintln(w, "────────\t─────────────────────\t─────\t───────\t─────────\t───────")
+		fmt.Fprintln(w, "Version\tTimestamp\tAdded\tChanged\tDestroyed\tStatus\tTags\tMessage")
+		fmt.Fprintln(w, "────────\t─────────────────────\t─────\t───────\t─────────\t───────\t────\t───────")
 
 		// Step 8: Print each version
 		for _, v := range versions {
@@ -128,36 +106,135 @@ Shows version number, timestamp, and resource change statistics for each snapsho
 			if v.Version == currentVersion && currentStatus {
 				status = "Active"
 			}
+			if v.Failed() {
+				if status == "-" {
+					status = "Failed"
+				} else {
+					status += ", failed"
+				}
+			}
+			if v.IsPinned() {
+				if status == "-" {
+					status = "Pinned"
+				} else {
+					status += ", pinned"
+				}
+			}
 
-			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n",
+			message := v.Message
+			if message == "" {
+				message = "-"
+			}
+
+			fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%d\t%s\t%s\t%s\n",
 				versionDisplay,
 				v.Timestamp,
-				v.Added,
-				v.Changed,
-				v.Destroyed,
-				status)
+				v.Resources.Added,
+				v.Resources.Changed,
+				v.Resources.Destroyed,
+				status,
+				formatTags(v),
+				message)
 		}
 
 		w.Flush()
 
-		// Step 9: Display footer
+		// Step 9: Optionally show resource-level changes from the stored plans
+		if listChanges {
+			fmt.Println()
+			for _, v := range versions {
+				showVersionChanges(cloudtmDir, v.Version)
+				if v.Failed() {
+					showVersionFailure(cloudtmDir, v)
+				}
+			}
+		}
+
+		// Step 10: Display footer
 		fmt.Println("──────────────────────────────────────────────────────────────")
-		fmt.Println("Use: cloudtm rollback --to <version>")
+		reportMalformedMetadata(badMeta)
+		fmt.Println("Use: cloudtm rollback --to <version|tag>")
 		fmt.Println()
 	},
 }
 
-// extractVersionNumber extracts numeric part from version string (e.g., "v10" -> 10)
-func extractVersionNumber(version string) int {
-	numStr := strings.TrimPrefix(version, "v")
-	num, err := strconv.Atoi(numStr)
+// filterVersions keeps the versions named by refs (version names or tags),
+// exiting when a ref matches no version
+func filterVersions(cloudtmDir string, versions []*helper.Metadata, refs []string) []*helper.Metadata {
+	store := localStore(cloudtmDir)
+	wanted := make(map[string]bool)
+	for _, ref := range refs {
+		wanted[resolveVersion(store, ref)] = true
+	}
+
+	var filtered []*helper.Metadata
+	for _, v := range versions {
+		if wanted[v.Version] {
+			filtered = append(filtered, v)
+			delete(wanted, v.Version)
+		}
+	}
+	for v := range wanted {
+		fmt.Printf("❌ Version '%s' does not exist\n", v)
+		exit(1)
+	}
+	return filtered
+}
+
+// showVersionChanges prints the resource changes recorded in a version's plan
+func showVersionChanges(cloudtmDir, version string) {
+	fmt.Printf("%s:\n", version)
+
+	plan, err := helper.LoadVersionPlan(localStore(cloudtmDir), version)
+	if err != nil {
+		fmt.Println("  (no plan recorded)")
+		return
+	}
+
+	changes := plan.Changes()
+	if len(changes) == 0 {
+		fmt.Println("  (no resource changes)")
+		return
+	}
+	for _, c := range changes {
+		fmt.Printf("  %-8s %s\n", c.Action, c.Address)
+	}
+}
+
+// showVersionFailure prints the changes a failed apply completed and the
+// error output stored with the version
+func showVersionFailure(cloudtmDir string, meta *helper.Metadata) {
+	fmt.Println("  ❌ Apply failed; completed before the error:")
+	if len(meta.Changes) == 0 {
+		fmt.Println("    (no resource changes)")
+	}
+	for _, c := range meta.Changes {
+		fmt.Printf("    %-8s %s\n", c.Action, c.Address)
+	}
+
+	log, err := helper.LoadErrorLog(localStore(cloudtmDir), meta.Version)
 	if err != nil {
-		return 0
+		return
+	}
+	for _, line := range strings.Split(strings.TrimRight(string(log), "\n"), "\n") {
+		fmt.Printf("    %s\n", line)
+	}
+}
+
+// reportMalformedMetadata lists metadata files that could not be decoded
+func reportMalformedMetadata(errs []helper.MetadataError) {
+	if len(errs) == 0 {
+		return
+	}
+	fmt.Printf("⚠️  Skipped %d malformed metadata file(s):\n", len(errs))
+	for _, e := range errs {
+		fmt.Printf("   - meta/%s\n", e.Error())
 	}
-	return num
 }
 
 func init() {
+	listCmd.Flags().BoolVar(&listTimeline, "timeline", false, "Show the timeline of applies, destroys, rollbacks and deletions")
+	listCmd.Flags().BoolVar(&listChanges, "changes", false, "Show resource-level changes of each version")
 	rootCmd.AddCommand(listCmd)
 }
 
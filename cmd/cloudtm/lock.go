@@ -0,0 +1,150 @@
+package cloudtm
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/raxkumar/cloudtm/helper"
+	"github.com/spf13/cobra"
+)
+
+// annotationMutating marks commands that change .cloudtm and must hold the
+// lock while they run. Read-only commands (list, diff, log, ...) never block.
+const annotationMutating = "cloudtm/mutating"
+
+// mutatesWhen decides, from the parsed flags, whether an invocation of a
+// command annotated as mutating changes .cloudtm (e.g. rollback without flags
+// only shows its status)
+var mutatesWhen = map[*cobra.Command]func() bool{}
+
+var lockTimeout time.Duration
+
+// heldLock is the lock held by the running command, released when it exits
+var heldLock *helper.LockInfo
+var heldLockDir string
+
+// acquireLock takes the .cloudtm lock for mutating commands, waiting up to
+// --lock-timeout while another cloudtm process holds it
+func acquireLock(cmd *cobra.Command) {
+	if !isMutating(cmd) {
+		return
+	}
+	cwd, _ := os.Getwd()
+	cloudtmDir := filepath.Join(cwd, ".cloudtm")
+	if _, err := os.Stat(cloudtmDir); err != nil {
+		// Not initialized yet; the command reports it
+		return
+	}
+
+	info := helper.NewLockInfo(cmd.CommandPath())
+	err := helper.AcquireLock(cloudtmDir, info, 0)
+	var locked *helper.LockedError
+	if errors.As(err, &locked) && lockTimeout > 0 {
+		fmt.Printf("⏳ Waiting up to %s for the lock held by %s...\n", lockTimeout, locked.Holder)
+		err = helper.AcquireLock(cloudtmDir, info, lockTimeout)
+	}
+	if errors.As(err, &locked) {
+		fmt.Println("❌ Error:", err)
+		fmt.Println("💡 Wait for it to finish or retry with --lock-timeout 5m")
+		fmt.Println("💡 If that process is gone, run: cloudtm force-unlock")
+		exit(1)
+	}
+	if err != nil {
+		fmt.Println("❌ Error acquiring lock:", err)
+		exit(1)
+	}
+	heldLock, heldLockDir = info, cloudtmDir
+
+	// With the lock held no other run is writing, so anything half-written
+	// was left by an interrupted one
+	cleanInterruptedWrites(cloudtmDir)
+}
+
+// isMutating reports whether this invocation of cmd must hold the lock
+func isMutating(cmd *cobra.Command) bool {
+	if cmd.Annotations[annotationMutating] == "" {
+		return false
+	}
+	if mutates, ok := mutatesWhen[cmd]; ok {
+		return mutates()
+	}
+	return true
+}
+
+// cleanInterruptedWrites removes incomplete versions and temporary files left
+// in .cloudtm by a run that crashed or was killed
+func cleanInterruptedWrites(cloudtmDir string) {
+	incomplete, err := helper.CleanStaging(cloudtmDir)
+	for _, version := range incomplete {
+		fmt.Printf("🧹 Removed incomplete version %s left by an interrupted run\n", version)
+	}
+	if err != nil {
+		fmt.Println("⚠️  Warning: Failed to clean up after an interrupted run:", err)
+	}
+}
+
+// releaseLock releases the lock held by the running command, if any
+func releaseLock() {
+	if heldLock == nil {
+		return
+	}
+	if err := helper.ReleaseLock(heldLockDir, heldLock); err != nil {
+		fmt.Println("⚠️  Warning: Failed to release lock:", err)
+	}
+	heldLock = nil
+}
+
+var forceUnlockYes bool
+
+var forceUnlockCmd = &cobra.Command{
+	Use:   "force-unlock",
+	Short: "remove a stale .cloudtm lock",
+	Long: `Removes the lock file (.cloudtm/lock.json) that mutating commands hold while
+they run. Use it only when the holder is gone, e.g. after a crash on another
+machine; locks left by a process on this host that no longer runs are
+cleared automatically.
+
+Usage:
+    cloudtm force-unlock             # Show the holder and ask for confirmation
+    cloudtm force-unlock --yes       # Remove without asking`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		cwd, _ := os.Getwd()
+		cloudtmDir := filepath.Join(cwd, ".cloudtm")
+
+		if _, err := os.Stat(cloudtmDir); os.IsNotExist(err) {
+			fmt.Println("❌ CloudTimeMachine not initialized. Run: cloudtm init")
+			exit(1)
+		}
+
+		holder, err := helper.ReadLock(cloudtmDir)
+		if err != nil {
+			fmt.Println("⚠️  Warning: Could not read lock:", err)
+		} else if holder == nil {
+			fmt.Println("ℹ️  The .cloudtm directory is not locked")
+			return
+		} else {
+			fmt.Printf("🔒 Locked by %s\n", holder)
+		}
+
+		if !forceUnlockYes && !confirm("\nRemoving the lock while its holder is still running can corrupt versions.\n  Only 'yes' will be accepted to confirm.\n\n  Enter a value: ") {
+			fmt.Println("\n❌ Force-unlock cancelled.")
+			return
+		}
+
+		if _, err := helper.ForceUnlock(cloudtmDir); err != nil {
+			fmt.Println("❌ Error removing lock:", err)
+			exit(1)
+		}
+		fmt.Println("✅ Lock removed")
+	},
+}
+
+func init() {
+	rootCmd.PersistentFlags().DurationVar(&lockTimeout, "lock-timeout", 0, "How long to wait for the .cloudtm lock held by another cloudtm process (e.g. 30s, 5m)")
+	forceUnlockCmd.Flags().BoolVarP(&forceUnlockYes, "yes", "y", false, "Remove the lock without asking for confirmation")
+	rootCmd.AddCommand(forceUnlockCmd)
+}
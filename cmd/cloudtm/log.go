@@ -0,0 +1,134 @@
+package cloudtm
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/raxkumar/cloudtm/helper"
+	"github.com/spf13/cobra"
+)
+
+var logCommand string
+var logUser string
+var logSince string
+var logUntil string
+var logLimit int
+var logJSON bool
+
+var logCmd = &cobra.Command{
+	Use:   "log",
+	Short: "show the audit log of cloudtm operations",
+	Long: `Shows the entries of .cloudtm/audit.log, newest first. Every cloudtm command
+run in the project is recorded with its arguments, OS user, hostname, git
+commit, Terraform version, start and end times, exit status and the current
+version afterwards. The log is append-only.
+
+Times given to --since and --until are RFC 3339 timestamps, dates
+(YYYY-MM-DD) or durations before now (e.g. 12h, 7d, 2w).
+
+Usage:
+    cloudtm log                          # All entries
+    cloudtm log --command destroy        # Only destroys
+    cloudtm log --user alice --since 7d  # One user's operations this week
+    cloudtm log --since 2025-11-01 --until 2025-11-30
+    cloudtm log -n 20 --json             # Last 20 entries as JSON Lines`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		cwd, _ := os.Getwd()
+		cloudtmDir := filepath.Join(cwd, ".cloudtm")
+
+		if _, err := os.Stat(cloudtmDir); os.IsNotExist(err) {
+			fmt.Println("❌ CloudTimeMachine not initialized. Run: cloudtm init")
+			os.Exit(1)
+		}
+
+		// Step 1: Build the filter
+		now := time.Now()
+		filter := helper.AuditFilter{Command: strings.TrimSpace(logCommand), User: logUser}
+		var err error
+		if logSince != "" {
+			if filter.Since, err = helper.ParseAuditTime(logSince, now); err != nil {
+				fmt.Println("❌", err)
+				os.Exit(1)
+			}
+		}
+		if logUntil != "" {
+			if filter.Until, err = helper.ParseAuditTime(logUntil, now); err != nil {
+				fmt.Println("❌", err)
+				os.Exit(1)
+			}
+		}
+
+		// Step 2: Read and filter the log, newest first
+		entries, err := helper.ReadAudit(cloudtmDir)
+		if err != nil {
+			fmt.Println("❌ Error reading audit log:", err)
+			os.Exit(1)
+		}
+		var matched []helper.AuditEntry
+		for i := len(entries) - 1; i >= 0; i-- {
+			if filter.Match(&entries[i]) {
+				matched = append(matched, entries[i])
+			}
+			if logLimit > 0 && len(matched) == logLimit {
+				break
+			}
+		}
+
+		if logJSON {
+			enc := json.NewEncoder(os.Stdout)
+			for i := range matched {
+				enc.Encode(&matched[i])
+			}
+			return
+		}
+
+		if len(matched) == 0 {
+			fmt.Println("ℹ️  No matching audit log entries")
+			return
+		}
+
+		// Step 3: Display the entries
+		fmt.Println("\n📜 CloudTimeMachine Audit Log")
+		fmt.Println("──────────────────────────────────────────────────────────────")
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "Start\tDuration\tUser\tCommand\tExit\tVersion\tTerraform\tCommit")
+		fmt.Fprintln(w, "─────────────────────\t────────\t────\t───────\t────\t───────\t─────────\t──────")
+		for _, e := range matched {
+			start := e.Start
+			if t, err := e.StartTime(); err == nil {
+				start = t.Format(time.RFC3339)
+			}
+			commit := e.GitCommit
+			if len(commit) > 8 {
+				commit = commit[:8]
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s@%s\t%s\t%d\t%s\t%s\t%s\n",
+				start,
+				e.Duration().Round(time.Millisecond),
+				e.User, e.Host,
+				strings.Join(e.Args, " "),
+				e.ExitStatus,
+				orDash(e.Version),
+				orDash(e.TerraformVersion),
+				orDash(commit))
+		}
+		w.Flush()
+		fmt.Println("──────────────────────────────────────────────────────────────")
+	},
+}
+
+func init() {
+	logCmd.Flags().StringVar(&logCommand, "command", "", "Only show entries of this command (e.g. destroy, rollback)")
+	logCmd.Flags().StringVar(&logUser, "user", "", "Only show entries of this OS user")
+	logCmd.Flags().StringVar(&logSince, "since", "", "Only show entries started at or after this time")
+	logCmd.Flags().StringVar(&logUntil, "until", "", "Only show entries started at or before this time")
+	logCmd.Flags().IntVarP(&logLimit, "limit", "n", 0, "Show at most this many entries")
+	logCmd.Flags().BoolVar(&logJSON, "json", false, "Print matching entries as JSON Lines")
+	rootCmd.AddCommand(logCmd)
+}
@@ -0,0 +1,74 @@
+package cloudtm
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/raxkumar/cloudtm/helper"
+	"github.com/spf13/cobra"
+)
+
+var planOut string
+var planTF terraformFlags
+
+var planCmd = &cobra.Command{
+	Use:   "plan",
+	Short: "show the changes apply would make (wrapper around Terraform plan)",
+	Long: `Runs 'terraform plan -json' and renders the planned changes.
+Nothing is applied and no version is created.
+
+Variables, targets and other options are forwarded to Terraform: use the flags
+below or pass any Terraform flag after "--".
+
+Usage:
+    cloudtm plan
+    cloudtm plan --out tfplan       # Also save the plan to a file
+    cloudtm plan --var-file prod.tfvars --target aws_instance.web
+    cloudtm plan -- -lock-timeout=5m`,
+	Args:        terraformPassthroughArgs,
+	Annotations: map[string]string{annotationTerraform: "true"},
+	Run: func(cmd *cobra.Command, args []string) {
+		// Step 1: Select the Terraform-compatible binary
+		requireBinary()
+
+		// Step 2: Verify CloudTimeMachine is initialized
+		cwd, _ := os.Getwd()
+		cloudtmDir := filepath.Join(cwd, ".cloudtm")
+
+		if _, err := os.Stat(cloudtmDir); os.IsNotExist(err) {
+			fmt.Println("❌ CloudTimeMachine not initialized. Run: cloudtm init")
+			exit(1)
+		}
+
+		// Step 3: Run terraform plan
+		tfArgs := []string{"plan", "-json", "-input=false"}
+		if planOut != "" {
+			tfArgs = append(tfArgs, "-out="+planOut)
+		}
+		tfArgs = append(tfArgs, planTF.args(cmd, args)...)
+
+		fmt.Println("🚀 Running 'terraform plan -json'...")
+		result, err := helper.RunTerraformJSON(cwd, tfArgs, os.Stdout)
+		if err != nil {
+			fmt.Println("\n❌ Terraform plan failed:", err)
+			exit(1)
+		}
+
+		// Step 4: Summarize
+		if !result.Summary.HasChanges() {
+			fmt.Println("\n✅ No changes. Infrastructure matches the configuration.")
+			return
+		}
+		if planOut != "" {
+			fmt.Printf("\n📋 Plan saved to: %s\n", planOut)
+		}
+		fmt.Println("💡 Run 'cloudtm apply' to apply these changes and create a new version.")
+	},
+}
+
+func init() {
+	planCmd.Flags().StringVar(&planOut, "out", "", "Write the plan to the given file")
+	planTF.register(planCmd)
+	rootCmd.AddCommand(planCmd)
+}
@@ -0,0 +1,211 @@
+package cloudtm
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/raxkumar/cloudtm/helper"
+	"github.com/spf13/cobra"
+)
+
+var (
+	pruneKeepLast    int
+	pruneKeepWithin  string
+	pruneKeepDaily   int
+	pruneKeepWeekly  int
+	pruneKeepMonthly int
+	pruneDryRun      bool
+)
+
+var pruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "delete old versions according to a retention policy",
+	Long: `Deletes versions that no retention rule keeps, then removes stored file
+blobs no remaining version references.
+
+A version is kept when any of these selects it:
+    --keep-last N        the newest N versions
+    --keep-within D      versions created within D of now (e.g. 30d, 2w, 12h)
+    --keep-daily N       the newest version of each of the last N days
+    --keep-weekly N      the newest version of each of the last N weeks
+    --keep-monthly N     the newest version of each of the last N months
+    pinned versions      see 'cloudtm pin'
+
+The versions referenced by current.json and rollback.json are never deleted.
+Version numbers are not reused after pruning.
+
+Usage:
+    cloudtm prune --keep-last 10 --dry-run
+    cloudtm prune --keep-within 30d --keep-weekly 12`,
+	Args:        cobra.NoArgs,
+	Annotations: map[string]string{annotationMutating: "true"},
+	Run: func(cmd *cobra.Command, args []string) {
+		// Step 1: Verify CloudTimeMachine is initialized
+		cwd, _ := os.Getwd()
+		cloudtmDir := filepath.Join(cwd, ".cloudtm")
+
+		if _, err := os.Stat(cloudtmDir); os.IsNotExist(err) {
+			fmt.Println("❌ CloudTimeMachine not initialized. Run: cloudtm init")
+			exit(1)
+		}
+
+		// Step 2: Build the retention policy
+		policy := helper.RetentionPolicy{
+			KeepLast:    pruneKeepLast,
+			KeepDaily:   pruneKeepDaily,
+			KeepWeekly:  pruneKeepWeekly,
+			KeepMonthly: pruneKeepMonthly,
+		}
+		if pruneKeepWithin != "" {
+			within, err := helper.ParseRetentionDuration(pruneKeepWithin)
+			if err != nil {
+				fmt.Println("❌", err)
+				exit(1)
+			}
+			policy.KeepWithin = within
+		}
+		if policy.IsEmpty() {
+			fmt.Println("❌ No retention policy given. Use --keep-last, --keep-within, --keep-daily, --keep-weekly or --keep-monthly.")
+			exit(1)
+		}
+
+		// Step 3: Protect the versions in use
+		protected := make(map[string]string)
+		currentVersion, _, err := helper.GetCurrentVersion(cloudtmDir)
+		if err != nil {
+			fmt.Println("❌ Error reading current.json:", err)
+			exit(1)
+		}
+		if currentVersion != "" {
+			protected[currentVersion] = "current"
+		}
+		rollbackVersion, err := helper.GetRollbackVersion(cloudtmDir)
+		if err != nil {
+			fmt.Println("❌ Error reading rollback.json:", err)
+			exit(1)
+		}
+		if rollbackVersion != "" {
+			protected[rollbackVersion] = "rollback"
+		}
+
+		// Step 4: Decide which versions to keep
+		versions, badMeta, err := helper.LoadAllMetadata(cloudtmDir)
+		if err != nil {
+			fmt.Println("❌ Error reading meta directory:", err)
+			exit(1)
+		}
+		reportMalformedMetadata(badMeta)
+		if len(badMeta) > 0 {
+			fmt.Println("ℹ️  Versions with malformed metadata are left untouched.")
+		}
+
+		decisions := helper.ApplyRetention(versions, policy, protected, time.Now().UTC())
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "Version\tTimestamp\tAction\tReason")
+		fmt.Fprintln(w, "────────\t─────────────────────\t──────\t──────")
+		var remove []string
+		for _, d := range decisions {
+			if d.Keep {
+				fmt.Fprintf(w, "%s\t%s\tkeep\t%s\n", d.Meta.Version, d.Meta.Timestamp, strings.Join(d.Reasons, ", "))
+				continue
+			}
+			fmt.Fprintf(w, "%s\t%s\tdelete\t-\n", d.Meta.Version, d.Meta.Timestamp)
+			remove = append(remove, d.Meta.Version)
+		}
+		w.Flush()
+		fmt.Println("──────────────────────────────────────────────────────────────")
+
+		if pruneDryRun {
+			fmt.Printf("ℹ️  Dry run: %d version(s) would be deleted, %d kept\n", len(remove), len(decisions)-len(remove))
+			return
+		}
+
+		// Step 5: Delete versions, then the blobs only they referenced
+		store := localStore(cloudtmDir)
+		for _, v := range remove {
+			if err := helper.DeleteVersion(store, v); err != nil {
+				fmt.Printf("❌ Error deleting %s: %v\n", v, err)
+				exit(1)
+			}
+			fmt.Printf("🗑️  Deleted %s\n", v)
+			if auditEntry != nil {
+				auditEntry.Pruned = append(auditEntry.Pruned, v)
+			}
+
+			event := helper.NewHistoryEvent(helper.EventPrune)
+			event.Version = v
+			recordEvent(cloudtmDir, event)
+		}
+
+		collected, err := helper.GarbageCollect(store)
+		if err != nil {
+			fmt.Printf("❌ Error removing unreferenced objects (%d removed): %v\n", len(collected), err)
+			exit(1)
+		}
+
+		fmt.Printf("✅ Deleted %d version(s) and %d unreferenced object(s), kept %d version(s)\n",
+			len(remove), len(collected), len(decisions)-len(remove))
+	},
+}
+
+var pinRemove bool
+
+var pinCmd = &cobra.Command{
+	Use:   "pin <version|tag>...",
+	Short: "protect versions from being pruned",
+	Long: `Pins versions so 'cloudtm prune' never deletes them, whatever the retention policy.
+The pin is stored in the version's metadata. To pin a version under a name,
+use 'cloudtm tag <version> <name> --pin'.
+
+Usage:
+    cloudtm pin v3 v7                # Pin versions
+    cloudtm pin --remove v3          # Unpin a version`,
+	Args:        cobra.MinimumNArgs(1),
+	Annotations: map[string]string{annotationMutating: "true"},
+	Run: func(cmd *cobra.Command, args []string) {
+		cwd, _ := os.Getwd()
+		cloudtmDir := filepath.Join(cwd, ".cloudtm")
+
+		if _, err := os.Stat(cloudtmDir); os.IsNotExist(err) {
+			fmt.Println("❌ CloudTimeMachine not initialized. Run: cloudtm init")
+			exit(1)
+		}
+
+		for _, ref := range args {
+			version := resolveVersion(localStore(cloudtmDir), ref)
+			meta, err := helper.ReadMetadata(cloudtmDir, version)
+			if err != nil {
+				fmt.Printf("❌ Error reading metadata of '%s': %v\n", version, err)
+				exit(1)
+			}
+			meta.Pinned = !pinRemove
+			if _, err := helper.SaveMetadata(cloudtmDir, meta); err != nil {
+				fmt.Printf("❌ Error writing metadata of '%s': %v\n", version, err)
+				exit(1)
+			}
+			if pinRemove {
+				fmt.Printf("✅ Unpinned %s\n", version)
+			} else {
+				fmt.Printf("📌 Pinned %s\n", version)
+			}
+		}
+	},
+}
+
+func init() {
+	pruneCmd.Flags().IntVar(&pruneKeepLast, "keep-last", 0, "Keep the newest N versions")
+	pruneCmd.Flags().StringVar(&pruneKeepWithin, "keep-within", "", "Keep versions created within this duration (e.g. 30d, 2w, 12h)")
+	pruneCmd.Flags().IntVar(&pruneKeepDaily, "keep-daily", 0, "Keep the newest version of each of the last N days")
+	pruneCmd.Flags().IntVar(&pruneKeepWeekly, "keep-weekly", 0, "Keep the newest version of each of the last N weeks")
+	pruneCmd.Flags().IntVar(&pruneKeepMonthly, "keep-monthly", 0, "Keep the newest version of each of the last N months")
+	pruneCmd.Flags().BoolVar(&pruneDryRun, "dry-run", false, "Show what would be deleted without deleting anything")
+	rootCmd.AddCommand(pruneCmd)
+
+	pinCmd.Flags().BoolVar(&pinRemove, "remove", false, "Unpin the versions")
+	rootCmd.AddCommand(pinCmd)
+}
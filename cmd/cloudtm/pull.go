@@ -0,0 +1,49 @@
+package cloudtm
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+)
+
+var pullRemote string
+
+var pullCmd = &cobra.Command{
+	Use:   "pull [versions|tags...]",
+	Short: "download versions from the remote snapshot store",
+	Long: `Downloads versions that are missing from the local .cloudtm directory.
+Without arguments every remote version is pulled.
+
+Usage:
+    cloudtm pull                     # Pull all versions
+    cloudtm pull v3 v4               # Pull specific versions
+    cloudtm pull pre-migration       # Pull a tagged version
+    cloudtm pull --remote s3://bucket/app`,
+	Annotations: map[string]string{annotationMutating: "true"},
+	Run: func(cmd *cobra.Command, args []string) {
+		cwd, _ := os.Getwd()
+		cloudtmDir := filepath.Join(cwd, ".cloudtm")
+
+		if _, err := os.Stat(cloudtmDir); os.IsNotExist(err) {
+			fmt.Println("❌ CloudTimeMachine not initialized. Run: cloudtm init")
+			exit(1)
+		}
+
+		remote := openRemote(cloudtmDir, pullRemote)
+		fmt.Printf("🚀 Pulling versions from %s...\n", remote)
+
+		copied, conflicts := syncVersions(remote, localStore(cloudtmDir), args, "⬇️  Pulled")
+		fmt.Printf("\n✅ Pulled %d version(s)\n", copied)
+		if conflicts > 0 {
+			fmt.Printf("⚠️  %d version(s) conflict with local versions\n", conflicts)
+			exit(1)
+		}
+	},
+}
+
+func init() {
+	pullCmd.Flags().StringVar(&pullRemote, "remote", "", "Remote store location (defaults to the configured remote)")
+	rootCmd.AddCommand(pullCmd)
+}
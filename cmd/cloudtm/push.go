@@ -0,0 +1,49 @@
+package cloudtm
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+)
+
+var pushRemote string
+
+var pushCmd = &cobra.Command{
+	Use:   "push [versions|tags...]",
+	Short: "upload local versions to the remote snapshot store",
+	Long: `Uploads versions that are missing from the remote snapshot store.
+Without arguments every local version is pushed.
+
+Usage:
+    cloudtm push                     # Push all versions
+    cloudtm push v3 v4               # Push specific versions
+    cloudtm push pre-migration       # Push a tagged version
+    cloudtm push --remote s3://bucket/app`,
+	Annotations: map[string]string{annotationMutating: "true"},
+	Run: func(cmd *cobra.Command, args []string) {
+		cwd, _ := os.Getwd()
+		cloudtmDir := filepath.Join(cwd, ".cloudtm")
+
+		if _, err := os.Stat(cloudtmDir); os.IsNotExist(err) {
+			fmt.Println("❌ CloudTimeMachine not initialized. Run: cloudtm init")
+			exit(1)
+		}
+
+		remote := openRemote(cloudtmDir, pushRemote)
+		fmt.Printf("🚀 Pushing versions to %s...\n", remote)
+
+		copied, conflicts := syncVersions(localStore(cloudtmDir), remote, args, "⬆️  Pushed")
+		fmt.Printf("\n✅ Pushed %d version(s)\n", copied)
+		if conflicts > 0 {
+			fmt.Printf("⚠️  %d version(s) conflict with the remote\n", conflicts)
+			exit(1)
+		}
+	},
+}
+
+func init() {
+	pushCmd.Flags().StringVar(&pushRemote, "remote", "", "Remote store location (defaults to the configured remote)")
+	rootCmd.AddCommand(pushCmd)
+}
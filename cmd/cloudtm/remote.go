@@ -0,0 +1,156 @@
+package cloudtm
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/raxkumar/cloudtm/helper"
+	"github.com/spf13/cobra"
+)
+
+var remoteCmd = &cobra.Command{
+	Use:   "remote [location]",
+	Short: "show or set the remote snapshot store",
+	Long: `Shows or sets the remote snapshot store used by 'cloudtm push' and 'cloudtm pull'.
+The setting is stored in .cloudtm/config.json.
+
+Supported locations:
+    /mnt/shared/cloudtm              # Local or mounted filesystem
+    file:///mnt/shared/cloudtm       # Same, as a URL
+    s3://bucket/prefix               # S3-compatible object storage
+
+S3 settings are read from the environment:
+    AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY, AWS_SESSION_TOKEN
+    AWS_REGION                       # defaults to us-east-1
+    CLOUDTM_S3_ENDPOINT              # e.g. http://localhost:9000 for MinIO
+
+Usage:
+    cloudtm remote                   # Show the configured remote
+    cloudtm remote s3://bucket/app   # Set the remote`,
+	Args:        cobra.MaximumNArgs(1),
+	Annotations: map[string]string{annotationMutating: "true"},
+	Run: func(cmd *cobra.Command, args []string) {
+		cwd, _ := os.Getwd()
+		cloudtmDir := filepath.Join(cwd, ".cloudtm")
+
+		if _, err := os.Stat(cloudtmDir); os.IsNotExist(err) {
+			fmt.Println("❌ CloudTimeMachine not initialized. Run: cloudtm init")
+			exit(1)
+		}
+
+		cfg, err := helper.LoadConfig(cloudtmDir)
+		if err != nil {
+			fmt.Println("❌ Error reading config.json:", err)
+			exit(1)
+		}
+
+		if len(args) == 0 {
+			if cfg.Remote == "" {
+				fmt.Println("ℹ️  No remote configured. Set one with: cloudtm remote <location>")
+				return
+			}
+			fmt.Printf("Remote: %s\n", cfg.Remote)
+			return
+		}
+
+		if _, err := helper.OpenStore(args[0]); err != nil {
+			fmt.Println("❌ Invalid remote:", err)
+			exit(1)
+		}
+		cfg.Remote = args[0]
+		if err := helper.SaveConfig(cloudtmDir, cfg); err != nil {
+			fmt.Println("❌ Error writing config.json:", err)
+			exit(1)
+		}
+		fmt.Printf("✅ Remote set to: %s\n", cfg.Remote)
+	},
+}
+
+// openRemote opens the remote store from the flag value or the configured
+// remote. Snapshot content is encrypted with the project's key, if any.
+func openRemote(cloudtmDir, location string) helper.SnapshotStore {
+	if location == "" {
+		cfg, err := helper.LoadConfig(cloudtmDir)
+		if err != nil {
+			fmt.Println("❌ Error reading config.json:", err)
+			exit(1)
+		}
+		location = cfg.Remote
+	}
+	if location == "" {
+		fmt.Println("❌ No remote configured. Run: cloudtm remote <location> or pass --remote")
+		exit(1)
+	}
+
+	store, err := helper.OpenStore(location)
+	if err != nil {
+		fmt.Println("❌ Error opening remote:", err)
+		exit(1)
+	}
+	return helper.WithEncryption(store, encryptionKey(cloudtmDir))
+}
+
+// syncVersions copies versions missing in dst from src; tags in versions are
+// resolved against src. Versions present in both with different timestamps
+// are reported as conflicts and left alone. It returns the number of versions
+// copied and the number of conflicts.
+func syncVersions(src, dst helper.SnapshotStore, versions []string, arrow string) (int, int) {
+	for i, ref := range versions {
+		versions[i] = resolveVersion(src, ref)
+	}
+	if len(versions) == 0 {
+		var err error
+		versions, err = helper.StoreVersions(src)
+		if err != nil {
+			fmt.Printf("❌ Error listing versions in %s: %v\n", src, err)
+			exit(1)
+		}
+	}
+	sort.Slice(versions, func(i, j int) bool {
+		return helper.VersionNumber(versions[i]) < helper.VersionNumber(versions[j])
+	})
+
+	dstVersions, err := helper.StoreVersions(dst)
+	if err != nil {
+		fmt.Printf("❌ Error listing versions in %s: %v\n", dst, err)
+		exit(1)
+	}
+	existing := make(map[string]bool)
+	for _, v := range dstVersions {
+		existing[v] = true
+	}
+
+	copied, conflicts := 0, 0
+	for _, v := range versions {
+		srcMeta, err := helper.ReadStoreMetadata(src, v)
+		if err != nil {
+			fmt.Printf("⚠️  Skipping %s: %v\n", v, err)
+			continue
+		}
+
+		if existing[v] {
+			dstMeta, err := helper.ReadStoreMetadata(dst, v)
+			if err == nil && dstMeta.Timestamp == srcMeta.Timestamp {
+				fmt.Printf("✅ %s already up to date\n", v)
+				continue
+			}
+			fmt.Printf("⚠️  Conflict: %s exists in %s with different content — skipped\n", v, dst)
+			conflicts++
+			continue
+		}
+
+		if err := helper.CopyVersion(src, dst, v); err != nil {
+			fmt.Printf("❌ Error copying %s: %v\n", v, err)
+			exit(1)
+		}
+		fmt.Printf("%s %s\n", arrow, v)
+		copied++
+	}
+	return copied, conflicts
+}
+
+func init() {
+	rootCmd.AddCommand(remoteCmd)
+}
@@ -1,10 +1,9 @@
 package cloudtm
 
 import (
-	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
-	"os/exec"
 	"path/filepath"
 
 	"github.com/raxkumar/cloudtm/helper"
@@ -13,6 +12,11 @@ import (
 
 var rollbackTo string
 var deleteRollback bool
+var rollbackForce bool
+var rollbackInPlace bool
+var rollbackAutoApprove bool
+var rollbackDryRun bool
+var promoteRollback bool
 
 var rollbackCmd = &cobra.Command{
 	Use:   "rollback",
@@ -21,17 +25,49 @@ var rollbackCmd = &cobra.Command{
 
 Usage:
     cloudtm rollback --to vN        # Rollback to specific version
+    cloudtm rollback --to <tag>     # Rollback to a tagged version
+    cloudtm rollback --to vN --in-place   # Converge live infrastructure to vN
+    cloudtm rollback --to vN --dry-run    # Show the plan without changing anything
+    cloudtm rollback --promote      # Make the active rollback the project baseline
     cloudtm rollback --del          # Delete active rollback
     cloudtm rollback --delete       # Delete active rollback (alias)
 
 Rollback Prerequisites:
 1. All resources must be destroyed (terraform.tfstate resources should be empty)
 2. No active rollback should be in progress (rollback.json should be empty)
+3. The version's state must have the same lineage as the live state
+   (override with --force)
+
+In-Place Mode (--in-place):
+- Restores the version's configuration files into the project root and
+  removes Terraform files the version does not have
+- Keeps the live state; nothing has to be destroyed first
+- Refuses a version whose state lineage differs from the live state
+  (override with --force)
+- Plans and, after confirmation (or with --auto-approve), applies only the
+  differences, then snapshots the result as a new version
+- The previous configuration is restored if the plan is cancelled or fails
+
+Dry-Run Mode (--dry-run):
+- Materializes the version in a scratch directory with a copy of the live state
+- Runs 'terraform init' and 'terraform plan' there and lists what would be
+  created, changed or destroyed
+- Warns when the version's state lineage differs from the live state
+- Never applies or pulls; a version only in the remote must be pulled first
+  with 'cloudtm pull vN'. The scratch directory is removed afterwards
+
+Promote Mode (--promote):
+- Moves the rollback's configuration and state into the project root
+  (the project's state must be empty)
+- Records the result as a new version restored from the rolled-back version
+- Removes the rollback directory and resets rollback.json without
+  destroying any resources
 
 Delete Mode:
 - Destroys resources in the rollback directory
 - Removes the rollback directory
 - Resets rollback.json`,
+	Annotations: map[string]string{annotationTerraform: "true", annotationMutating: "true"},
 	Run: func(cmd *cobra.Command, args []string) {
 		// Step 1: Get current working directory
 		cwd, _ := os.Getwd()
@@ -40,11 +76,11 @@ Delete Mode:
 		// Step 2: Verify CloudTimeMachine is initialized
 		if _, err := os.Stat(cloudtmDir); os.IsNotExist(err) {
 			fmt.Println("❌ CloudTimeMachine not initialized. Run: cloudtm init")
-			os.Exit(1)
+			exit(1)
 		}
 
 		// Step 3: If no flags provided, show current rollback status
-		if rollbackTo == "" && !deleteRollback {
+		if rollbackTo == "" && !deleteRollback && !rollbackInPlace && !rollbackDryRun && !promoteRollback {
 			showRollbackStatus(cloudtmDir)
 			return
 		}
@@ -53,10 +89,35 @@ Delete Mode:
 		if rollbackTo != "" && deleteRollback {
 			fmt.Println("❌ Error: --to and --del/--delete flags are mutually exclusive")
 			fmt.Println("Use either --to vN to rollback or --del to delete active rollback")
-			os.Exit(1)
+			exit(1)
 		}
 
-		// Step 5: Branch based on mode
+		if promoteRollback && (rollbackTo != "" || deleteRollback) {
+			fmt.Println("❌ Error: --promote cannot be combined with --to or --del/--delete")
+			exit(1)
+		}
+		if (rollbackInPlace || rollbackDryRun) && rollbackTo == "" {
+			fmt.Println("❌ Error: --in-place and --dry-run require --to vN")
+			exit(1)
+		}
+
+		// Step 5: Branch based on mode; every mode runs Terraform
+		requireBinary()
+		if promoteRollback {
+			// PROMOTE MODE: Make the active rollback the project baseline
+			handlePromoteRollback(cwd, cloudtmDir)
+			return
+		}
+		if rollbackDryRun {
+			// DRY-RUN MODE: Plan the version against the live state, change nothing
+			handleDryRunRollback(cwd, cloudtmDir)
+			return
+		}
+		if rollbackInPlace {
+			// IN-PLACE MODE: Converge the live infrastructure to the version
+			handleInPlaceRollback(cwd, cloudtmDir)
+			return
+		}
 		if deleteRollback {
 			// DELETE MODE: Clean up active rollback
 			handleDeleteRollback(cloudtmDir)
@@ -70,14 +131,14 @@ Delete Mode:
 		isEmpty, err := helper.IsStateEmpty(cwd)
 		if err != nil {
 			fmt.Println("❌ Error reading terraform.tfstate:", err)
-			os.Exit(1)
+			exit(1)
 		}
 		if !isEmpty {
 			fmt.Println("❌ Error: Resources still exist in terraform.tfstate")
 			fmt.Println("⚠️  You must destroy all resources before rollback")
 			fmt.Println("💡 Run: terraform destroy")
 			fmt.Println("💡 Or: cloudtm destroy")
-			os.Exit(1)
+			exit(1)
 		}
 		fmt.Println("✅ Terraform state is empty")
 
@@ -86,42 +147,50 @@ Delete Mode:
 		isRollbackEmpty, err := helper.IsRollbackEmpty(cloudtmDir)
 		if err != nil {
 			fmt.Println("❌ Error reading rollback.json:", err)
-			os.Exit(1)
+			exit(1)
 		}
 		if !isRollbackEmpty {
 			existingVersion, _ := helper.GetRollbackVersion(cloudtmDir)
 			fmt.Printf("❌ Error: Rollback to version '%s' is already applied\n", existingVersion)
 			fmt.Println("⚠️  You must destroy the rollback first")
 			fmt.Println("💡 Destroy resources in the rollback/ directory and reset rollback.json")
-			os.Exit(1)
+			exit(1)
 		}
 		fmt.Println("✅ No active rollback in progress")
 
-		// Step 8: Verify requested version exists
-		versionPath := filepath.Join(cloudtmDir, "versions", rollbackTo)
-		if _, err := os.Stat(versionPath); os.IsNotExist(err) {
-			fmt.Printf("❌ Error: Version '%s' does not exist\n", rollbackTo)
-			os.Exit(1)
+		// Step 8: Verify requested version exists, pulling it from the remote if needed
+		store := localStore(cloudtmDir)
+		version, err := ensureLocalVersion(cloudtmDir, store, rollbackTo, true)
+		if err != nil {
+			fmt.Printf("❌ Error: %v\n", err)
+			exit(1)
+		}
+		if version != rollbackTo {
+			fmt.Printf("🏷️  Tag '%s' names version '%s'\n", rollbackTo, version)
+			rollbackTo = version
 		}
 		fmt.Printf("✅ Found version '%s'\n", rollbackTo)
 
+		// Step 8b: Refuse to restore a state from a different lineage
+		checkRollbackLineage(store, cwd, rollbackTo)
+		warnBinaryChange(versionMetadata(store, rollbackTo))
+
 		// Step 9: Create rollback directory
 		rollbackDir := filepath.Join(cloudtmDir, "rollback")
 		if err := os.RemoveAll(rollbackDir); err != nil {
 			fmt.Println("❌ Error cleaning rollback directory:", err)
-			os.Exit(1)
+			exit(1)
 		}
 		if err := os.MkdirAll(rollbackDir, 0755); err != nil {
 			fmt.Println("❌ Error creating rollback directory:", err)
-			os.Exit(1)
+			exit(1)
 		}
 		fmt.Println("✅ Created rollback directory")
 
-		// Step 10: Copy tf_configs from version to rollback directory
-		tfConfigsSrc := filepath.Join(versionPath, "tf_configs")
-		if err := helper.CopyDirectory(tfConfigsSrc, rollbackDir, []string{}, []string{}, []string{}); err != nil {
+		// Step 10: Materialize the version's configs into the rollback directory
+		if err := helper.MaterializeVersion(store, rollbackTo, rollbackDir); err != nil {
 			fmt.Println("❌ Error copying configs to rollback directory:", err)
-			os.Exit(1)
+			exit(1)
 		}
 		fmt.Printf("✅ Copied configs from '%s' to rollback directory\n", rollbackTo)
 
@@ -136,8 +205,7 @@ Delete Mode:
 
 		// Step 12: Run terraform init in rollback directory
 		fmt.Println("\n🚀 Running 'terraform init' in rollback directory...")
-		initCmd := exec.Command("terraform", "init")
-		initCmd.Dir = rollbackDir
+		initCmd := helper.TerraformCommand(rollbackDir, "init")
 		initCmd.Stdout = os.Stdout
 		initCmd.Stderr = os.Stderr
 		initCmd.Stdin = os.Stdin
@@ -145,24 +213,30 @@ Delete Mode:
 		if err := initCmd.Run(); err != nil {
 			fmt.Println("\n❌ Terraform init failed in rollback directory:", err)
 			fmt.Println("⚠️  Rollback directory preserved for investigation")
-			os.Exit(1)
+			exit(1)
 		}
 		fmt.Println("✅ Terraform initialized successfully")
 
-		// Step 13: Run terraform apply --auto-approve in rollback directory
-		fmt.Println("\n🚀 Running 'terraform apply --auto-approve' in rollback directory...")
-		applyCmd := exec.Command("terraform", "apply", "--auto-approve")
-		applyCmd.Dir = rollbackDir
-		applyCmd.Stdout = os.Stdout
-		applyCmd.Stderr = os.Stderr
-		applyCmd.Stdin = os.Stdin
-
-		if err := applyCmd.Run(); err != nil {
+		// Step 13: Run terraform apply -auto-approve in rollback directory,
+		// with the variables the version was applied with
+		tfArgs, cleanupInputs := replayInputs(store, cloudtmDir, rollbackTo, cwd, rollbackDir)
+		fmt.Println("\n🚀 Running 'terraform apply -json -auto-approve' in rollback directory...")
+		result, err := helper.RunTerraformJSON(rollbackDir, append([]string{"apply", "-json", "-input=false", "-auto-approve"}, tfArgs...), os.Stdout)
+		// The replayed values are not needed past the apply; remove them
+		// before any exit, which skips deferred calls
+		cleanupInputs()
+		if err != nil {
 			fmt.Println("\n❌ Terraform apply failed in rollback directory:", err)
 			fmt.Println("⚠️  Rollback directory preserved for investigation")
-			os.Exit(1)
+			recordFailedRollback(cloudtmDir, helper.EventRollback, rollbackTo, result, err)
+			exit(1)
 		}
 
+		event := helper.NewHistoryEvent(helper.EventRollback)
+		event.Source = rollbackTo
+		event.Resources = helper.SummaryCounts(result.Summary)
+		recordEvent(cloudtmDir, event)
+
 		// Step 14: Update rollback.json
 		if err := helper.UpdateRollbackVersion(cloudtmDir, rollbackTo); err != nil {
 			fmt.Println("⚠️  Warning: Failed to update rollback.json:", err)
@@ -176,6 +250,447 @@ Delete Mode:
 	},
 }
 
+// recordFailedRollback records a failed Terraform run in the rollback
+// directory, with the resources it changed before the error
+func recordFailedRollback(cloudtmDir, kind, source string, result *helper.RunResult, err error) {
+	event := helper.NewHistoryEvent(kind)
+	event.Source = source
+	event.Status = helper.StatusFailed
+	event.Message = err.Error()
+	if result != nil {
+		summary := result.AppliedSummary()
+		event.Resources = helper.SummaryCounts(&summary)
+		if errs := result.Errors(); len(errs) > 0 {
+			event.Message = errs[0].Summary
+		}
+	}
+	recordEvent(cloudtmDir, event)
+}
+
+// ensureLocalVersion resolves a version name or tag and checks that the
+// version exists locally, pulling it from the configured remote store
+// otherwise. Without pull a version only found in the remote is an error, for
+// callers that do not hold the project lock. It returns the version name.
+func ensureLocalVersion(cloudtmDir string, store helper.SnapshotStore, ref string, pull bool) (string, error) {
+	version, err := helper.ResolveVersion(store, ref)
+	var unknown *helper.UnknownRefError
+	if err != nil && !errors.As(err, &unknown) {
+		return "", err
+	}
+	if err == nil {
+		exists, err := helper.VersionExists(store, version)
+		if err != nil {
+			return "", err
+		}
+		if exists {
+			return version, nil
+		}
+	}
+
+	cfg, cfgErr := helper.LoadConfig(cloudtmDir)
+	if cfgErr != nil || cfg.Remote == "" {
+		if unknown != nil {
+			return "", err
+		}
+		return "", fmt.Errorf("version '%s' does not exist", version)
+	}
+	remote, err := helper.OpenStore(cfg.Remote)
+	if err != nil {
+		return "", err
+	}
+	remote = helper.WithEncryption(remote, encryptionKey(cloudtmDir))
+	if unknown != nil {
+		// The tag may name a version that only exists in the remote
+		if version, err = helper.ResolveVersion(remote, ref); err != nil {
+			return "", fmt.Errorf("unknown version or tag '%s' locally or in %s", ref, remote)
+		}
+	}
+	if _, err := helper.ReadStoreMetadata(remote, version); err != nil {
+		return "", fmt.Errorf("version '%s' does not exist locally or in %s", version, remote)
+	}
+
+	if !pull {
+		return "", fmt.Errorf("version '%s' is only in %s; run 'cloudtm pull %s' first", version, remote, version)
+	}
+	fmt.Printf("⬇️  Pulling version '%s' from %s...\n", version, remote)
+	return version, helper.CopyVersion(remote, store, version)
+}
+
+// versionMetadata reads the metadata of a version, warning and returning nil
+// when it cannot be read
+func versionMetadata(store helper.SnapshotStore, version string) *helper.Metadata {
+	meta, err := helper.ReadStoreMetadata(store, version)
+	if err != nil {
+		fmt.Printf("⚠️  Warning: Could not read metadata of '%s': %v\n", version, err)
+		return nil
+	}
+	return meta
+}
+
+// checkRollbackLineage exits when the version's state belongs to a different
+// lineage than the live state, unless --force is given
+func checkRollbackLineage(store helper.SnapshotStore, cwd, version string) {
+	fmt.Println("🔍 Checking state lineage...")
+	err := rollbackLineage(store, cwd, version)
+	var mismatch *helper.LineageError
+	if err != nil && !errors.As(err, &mismatch) {
+		fmt.Println("❌ Error:", err)
+		exit(1)
+	}
+	if err == nil {
+		fmt.Println("✅ State lineage matches")
+		return
+	}
+	if rollbackForce {
+		fmt.Printf("⚠️  Warning: %v — continuing because of --force\n", err)
+		return
+	}
+	fmt.Printf("❌ Error: %v\n", err)
+	fmt.Println("⚠️  The version's state was not produced by the same stack as the live state.")
+	fmt.Println("💡 Use --force to roll back anyway")
+	exit(1)
+}
+
+// rollbackLineage compares the lineage of the version's state with the live
+// state and returns a *helper.LineageError when they differ
+func rollbackLineage(store helper.SnapshotStore, cwd, version string) error {
+	meta, err := helper.ReadStoreMetadata(store, version)
+	if err != nil {
+		return fmt.Errorf("reading metadata of '%s': %w", version, err)
+	}
+	versionState, err := helper.VersionStateInfo(store, meta)
+	if err != nil {
+		return fmt.Errorf("reading state of '%s': %w", version, err)
+	}
+	return helper.CheckLineage(version, versionState, liveStateInfo(cwd))
+}
+
+// handleInPlaceRollback restores the configuration of rollbackTo into the
+// project root and applies it against the live state, so Terraform only
+// changes what differs. The result is snapshotted as a new version.
+func handleInPlaceRollback(cwd, cloudtmDir string) {
+	// Step 1: Verify requested version exists, pulling it from the remote if needed
+	store := localStore(cloudtmDir)
+	version, err := ensureLocalVersion(cloudtmDir, store, rollbackTo, true)
+	if err != nil {
+		fmt.Printf("❌ Error: %v\n", err)
+		exit(1)
+	}
+	if version != rollbackTo {
+		fmt.Printf("🏷️  Tag '%s' names version '%s'\n", rollbackTo, version)
+		rollbackTo = version
+	}
+	fmt.Printf("✅ Found version '%s'\n", rollbackTo)
+
+	// Refuse to converge a live state from a different lineage
+	checkRollbackLineage(store, cwd, rollbackTo)
+
+	// Step 2: Keep the current configuration so it can be put back
+	backup, err := helper.BackupProject(cwd)
+	if err != nil {
+		fmt.Println("❌ Error reading project files:", err)
+		exit(1)
+	}
+	restoreBackup := func() {
+		if err := backup.Restore(cwd); err != nil {
+			fmt.Println("❌ Error restoring the previous configuration:", err)
+			exit(1)
+		}
+		fmt.Println("↩️  Restored the previous configuration")
+	}
+
+	// Step 3: Restore the version's configuration, keeping the live state
+	written, removed, err := helper.RestoreConfig(store, rollbackTo, cwd)
+	if err != nil {
+		fmt.Println("❌ Error restoring configuration:", err)
+		restoreBackup()
+		exit(1)
+	}
+	fmt.Printf("✅ Restored %d files from '%s' (live state kept)\n", len(written), rollbackTo)
+	for _, p := range removed {
+		fmt.Printf("🗑️  Removed %s (not in %s)\n", p, rollbackTo)
+	}
+
+	// Step 4: Initialize providers and modules of the restored configuration
+	fmt.Println("\n🚀 Running 'terraform init'...")
+	initCmd := helper.TerraformCommand(cwd, "init", "-input=false")
+	initCmd.Stdout = os.Stdout
+	initCmd.Stderr = os.Stderr
+	if err := initCmd.Run(); err != nil {
+		fmt.Println("\n❌ Terraform init failed:", err)
+		restoreBackup()
+		exit(1)
+	}
+
+	// Step 5: Plan, approve and apply only the differences, with the
+	// variables the version was applied with
+	tfArgs, cleanupInputs := replayInputs(store, cloudtmDir, rollbackTo, cwd, cwd)
+	var inputs *helper.TerraformInputs
+	if meta, err := helper.ReadStoreMetadata(store, rollbackTo); err == nil {
+		inputs = meta.Inputs
+		warnBinaryChange(meta)
+	}
+	applied, err := planAndApply(cwd, cloudtmDir, rollbackAutoApprove, tfArgs)
+	cleanupInputs()
+	defer os.Remove(pendingPlanFile(cloudtmDir))
+	if errors.Is(err, errApplyCancelled) {
+		fmt.Println("\n❌ Rollback cancelled.")
+		restoreBackup()
+		return
+	}
+	if err != nil && applied == nil {
+		// Nothing was applied, so the previous configuration still matches
+		// the live state
+		fmt.Println("\n❌ Rollback failed before anything was applied:", err)
+		restoreBackup()
+		os.Remove(pendingPlanFile(cloudtmDir))
+		exit(1)
+	}
+	if err != nil {
+		// The live state may already be partially converged, so the
+		// restored configuration is left in place for investigation
+		fmt.Println("\n❌ Terraform apply failed:", err)
+		recordFailedApply(store, cloudtmDir, cwd, snapshotCompression(cloudtmDir, ""), applied, func(meta *helper.Metadata) {
+			meta.Message = fmt.Sprintf("Rollback to %s (in-place)", rollbackTo)
+			meta.RestoredFrom = rollbackTo
+			meta.Inputs = inputs
+		})
+		fmt.Printf("⚠️  The configuration of '%s' is left in the project root\n", rollbackTo)
+		os.Remove(pendingPlanFile(cloudtmDir))
+		exit(1)
+	}
+
+	// Step 6: Snapshot the converged project as a new version
+	newVersion, err := createVersion(store, cloudtmDir, cwd, snapshotCompression(cloudtmDir, ""), applied, func(meta *helper.Metadata) {
+		meta.Message = fmt.Sprintf("Rollback to %s (in-place)", rollbackTo)
+		meta.RestoredFrom = rollbackTo
+		meta.State = liveStateInfo(cwd)
+		meta.Inputs = inputs
+		if applied != nil {
+			summary := applied.Plan.Summary()
+			if applied.Result.Summary != nil {
+				summary = *applied.Result.Summary
+			}
+			meta.Resources = helper.ResourceCounts{Added: summary.Add, Changed: summary.Change, Destroyed: summary.Remove}
+			meta.Changes = applied.Plan.Changes()
+		}
+	})
+	if err != nil {
+		fmt.Println("❌ Failed to create version:", err)
+		exit(1)
+	}
+
+	event := helper.NewHistoryEvent(helper.EventRollbackInPlace)
+	event.Version = newVersion
+	event.Source = rollbackTo
+	if applied != nil {
+		event.Resources = helper.SummaryCounts(applied.Result.Summary)
+	}
+	recordEvent(cloudtmDir, event)
+
+	fmt.Println("\n🎉 In-place rollback completed successfully!")
+	fmt.Printf("✅ Infrastructure converged to version %s, recorded as %s\n", rollbackTo, newVersion)
+}
+
+// handleDryRunRollback plans the configuration of rollbackTo against a copy
+// of the live state in a scratch directory and reports what would change
+func handleDryRunRollback(cwd, cloudtmDir string) {
+	// A dry run does not take the lock, so it never pulls
+	store := localStore(cloudtmDir)
+	version, err := ensureLocalVersion(cloudtmDir, store, rollbackTo, false)
+	if err != nil {
+		fmt.Printf("❌ Error: %v\n", err)
+		exit(1)
+	}
+	if version != rollbackTo {
+		fmt.Printf("🏷️  Tag '%s' names version '%s'\n", rollbackTo, version)
+		rollbackTo = version
+	}
+	fmt.Printf("✅ Found version '%s'\n", rollbackTo)
+	warnBinaryChange(versionMetadata(store, rollbackTo))
+
+	// Nothing is changed, so a lineage mismatch is only reported
+	var mismatch *helper.LineageError
+	if err := rollbackLineage(store, cwd, rollbackTo); errors.As(err, &mismatch) {
+		fmt.Printf("⚠️  Warning: %v — a rollback would need --force\n", err)
+	} else if err != nil {
+		fmt.Println("⚠️  Warning: Could not check state lineage:", err)
+	}
+
+	plan, err := planRollback(store, cwd, cloudtmDir, rollbackTo)
+	if err != nil {
+		fmt.Println("\n❌ Dry run failed:", err)
+		exit(1)
+	}
+
+	fmt.Printf("\n🔎 Rolling back to %s would:\n", rollbackTo)
+	fmt.Println("──────────────────────────────────────────────────────────────")
+	changes := plan.Changes()
+	if len(changes) == 0 {
+		fmt.Println("✅ Change nothing — the live infrastructure already matches")
+	}
+	symbols := map[string]string{"create": "+", "update": "~", "replace": "-/+", "delete": "-"}
+	for _, c := range changes {
+		symbol, ok := symbols[c.Action]
+		if !ok {
+			symbol = "?"
+		}
+		fmt.Printf("  %-3s %s (%s)\n", symbol, c.Address, c.Action)
+	}
+	fmt.Println("──────────────────────────────────────────────────────────────")
+	summary := plan.Summary()
+	fmt.Printf("Plan: %d to add, %d to change, %d to destroy.\n", summary.Add, summary.Change, summary.Remove)
+	fmt.Println("ℹ️  Dry run only — no infrastructure was changed")
+}
+
+// planRollback materializes a version in a scratch directory under
+// .cloudtm with a copy of the live state, runs 'terraform init' and
+// 'terraform plan' there and returns the plan. The scratch directory is
+// removed afterwards, or by the next mutating command if the dry run is killed.
+func planRollback(store helper.SnapshotStore, cwd, cloudtmDir, version string) (*helper.Plan, error) {
+	scratchDir, err := helper.CreateDryRunDir(cloudtmDir)
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(scratchDir)
+
+	// The version's own state is replaced by the live one
+	if _, err := helper.MaterializeFiles(store, version, scratchDir, helper.IsStateFile); err != nil {
+		return nil, fmt.Errorf("materializing %s: %w", version, err)
+	}
+	liveState := filepath.Join(cwd, helper.StateFileName)
+	if _, err := os.Stat(liveState); err == nil {
+		if err := helper.CopyFile(liveState, filepath.Join(scratchDir, helper.StateFileName)); err != nil {
+			return nil, fmt.Errorf("copying live state: %w", err)
+		}
+	}
+	fmt.Printf("✅ Materialized '%s' with the live state in a scratch directory\n", version)
+
+	fmt.Println("\n🚀 Running 'terraform init'...")
+	initCmd := helper.TerraformCommand(scratchDir, "init", "-input=false")
+	initCmd.Stdout = os.Stdout
+	initCmd.Stderr = os.Stderr
+	if err := initCmd.Run(); err != nil {
+		return nil, fmt.Errorf("terraform init: %w", err)
+	}
+
+	fmt.Println("\n🚀 Running 'terraform plan -json'...")
+	planFile := filepath.Join(scratchDir, "dry-run.tfplan")
+	planArgs := []string{"plan", "-json", "-input=false", "-lock=false", "-out=" + planFile}
+	// Dry runs do not hold the lock, so the replayed values are written to the
+	// scratch directory, which cleanup of other commands leaves alone
+	tfArgs, cleanupInputs := replayInputs(store, scratchDir, version, cwd, scratchDir)
+	defer cleanupInputs()
+	planArgs = append(planArgs, tfArgs...)
+	if _, err := helper.RunTerraformJSON(scratchDir, planArgs, os.Stdout); err != nil {
+		return nil, fmt.Errorf("terraform plan: %w", err)
+	}
+	planJSON, err := helper.OutputTerraform(scratchDir, "show", "-json", planFile)
+	if err != nil {
+		return nil, fmt.Errorf("terraform show -json: %w", err)
+	}
+	return helper.ParsePlan(planJSON)
+}
+
+// handlePromoteRollback moves the active rollback into the project root and
+// records it as a new version without destroying anything
+func handlePromoteRollback(cwd, cloudtmDir string) {
+	// Step 1: Find the active rollback
+	fmt.Println("🔍 Checking rollback status...")
+	rollbackVersion, err := helper.GetRollbackVersion(cloudtmDir)
+	if err != nil {
+		fmt.Println("❌ Error reading rollback.json:", err)
+		exit(1)
+	}
+	if rollbackVersion == "" {
+		fmt.Println("❌ Error: No active rollback to promote")
+		fmt.Println("💡 Run: cloudtm rollback --to vN")
+		exit(1)
+	}
+	rollbackDir := filepath.Join(cloudtmDir, "rollback")
+	if _, err := os.Stat(rollbackDir); err != nil {
+		fmt.Println("❌ Error: Rollback directory not found:", err)
+		exit(1)
+	}
+	fmt.Printf("✅ Found active rollback: %s\n", rollbackVersion)
+
+	// Step 2: The project's own state must not track resources that would be orphaned
+	fmt.Println("🔍 Checking terraform.tfstate...")
+	isEmpty, err := helper.IsStateEmpty(cwd)
+	if err != nil && !os.IsNotExist(err) {
+		fmt.Println("❌ Error reading terraform.tfstate:", err)
+		exit(1)
+	}
+	if err == nil && !isEmpty {
+		fmt.Println("❌ Error: Resources exist in the project's terraform.tfstate")
+		fmt.Println("⚠️  Promoting would replace that state and orphan them")
+		fmt.Println("💡 Run: cloudtm destroy")
+		exit(1)
+	}
+	fmt.Println("✅ Project state is empty")
+
+	// Step 3: Move the rollback's configuration and state into the project root
+	written, removed, err := helper.PromoteRollback(rollbackDir, cwd, rollbackVersion)
+	if err != nil {
+		fmt.Println("❌ Error copying the rollback into the project root:", err)
+		fmt.Println("⚠️  Rollback directory preserved; rollback.json unchanged")
+		exit(1)
+	}
+	fmt.Printf("✅ Copied %d files from the rollback directory\n", len(written))
+	for _, p := range removed {
+		fmt.Printf("🗑️  Removed %s (not in %s)\n", p, rollbackVersion)
+	}
+
+	// Step 4: Initialize providers and modules of the promoted configuration
+	fmt.Println("\n🚀 Running 'terraform init'...")
+	initCmd := helper.TerraformCommand(cwd, "init", "-input=false")
+	initCmd.Stdout = os.Stdout
+	initCmd.Stderr = os.Stderr
+	if err := initCmd.Run(); err != nil {
+		fmt.Println("⚠️  Warning: terraform init failed:", err)
+		fmt.Println("💡 Run 'terraform init' before the next apply")
+	}
+
+	// Step 5: Record the promoted project as a new version, keeping the
+	// inputs the rollback was applied with
+	store := localStore(cloudtmDir)
+	var inputs *helper.TerraformInputs
+	if meta, err := helper.ReadStoreMetadata(store, rollbackVersion); err == nil {
+		inputs = meta.Inputs
+	}
+	newVersion, err := createVersion(store, cloudtmDir, cwd, snapshotCompression(cloudtmDir, ""), nil, func(meta *helper.Metadata) {
+		meta.Message = fmt.Sprintf("Promote rollback of %s", rollbackVersion)
+		meta.RestoredFrom = rollbackVersion
+		meta.State = liveStateInfo(cwd)
+		meta.Inputs = inputs
+	})
+	if err != nil {
+		fmt.Println("❌ Failed to create version:", err)
+		fmt.Println("⚠️  Rollback directory preserved; rollback.json unchanged")
+		exit(1)
+	}
+
+	// Step 6: Clear the rollback without destroying its resources
+	if err := os.RemoveAll(rollbackDir); err != nil {
+		fmt.Println("❌ Error deleting rollback directory:", err)
+		exit(1)
+	}
+	fmt.Println("✅ Deleted rollback directory")
+	if err := helper.UpdateRollbackVersion(cloudtmDir, ""); err != nil {
+		fmt.Println("❌ Error resetting rollback.json:", err)
+		exit(1)
+	}
+	fmt.Println("✅ Reset rollback.json")
+
+	event := helper.NewHistoryEvent(helper.EventPromote)
+	event.Version = newVersion
+	event.Source = rollbackVersion
+	recordEvent(cloudtmDir, event)
+
+	fmt.Println("\n🎉 Rollback promoted successfully!")
+	fmt.Printf("✅ The project root now manages version %s, recorded as %s\n", rollbackVersion, newVersion)
+}
+
 func showRollbackStatus(cloudtmDir string) {
 	fmt.Println("\n🔄 Current Rollback Status")
 	fmt.Println("──────────────────────────────────────────────────────────────")
@@ -184,7 +699,7 @@ func showRollbackStatus(cloudtmDir string) {
 	rollbackVersion, err := helper.GetRollbackVersion(cloudtmDir)
 	if err != nil {
 		fmt.Println("❌ Error reading rollback.json:", err)
-		os.Exit(1)
+		exit(1)
 	}
 
 	if rollbackVersion == "" {
@@ -199,39 +714,29 @@ func showRollbackStatus(cloudtmDir string) {
 	fmt.Printf("Active Rollback: %s\n\n", rollbackVersion)
 
 	// Read metadata for the rollback version
-	metaFile := filepath.Join(cloudtmDir, "meta", rollbackVersion+".json")
-	data, err := os.ReadFile(metaFile)
+	meta, err := helper.ReadMetadata(cloudtmDir, rollbackVersion)
 	if err != nil {
-		fmt.Printf("⚠️  Warning: Could not read metadata for %s\n", rollbackVersion)
-		fmt.Println()
-		fmt.Println("Usage:")
-		fmt.Println("  cloudtm rollback --to vN        # Rollback to version")
-		fmt.Println("  cloudtm rollback --del          # Delete active rollback")
-		return
-	}
-
-	var meta map[string]interface{}
-	if err := json.Unmarshal(data, &meta); err != nil {
-		fmt.Printf("⚠️  Warning: Could not parse metadata for %s\n", rollbackVersion)
+		fmt.Printf("⚠️  Warning: Could not read metadata for %s: %v\n", rollbackVersion, err)
 		fmt.Println()
 		fmt.Println("Usage:")
 		fmt.Println("  cloudtm rollback --to vN        # Rollback to version")
+		fmt.Println("  cloudtm rollback --promote      # Keep the rollback as the new baseline")
 		fmt.Println("  cloudtm rollback --del          # Delete active rollback")
 		return
 	}
 
 	// Display metadata
-	resources, _ := meta["resources"].(map[string]interface{})
-	fmt.Printf("Version:    %s\n", meta["version"])
-	fmt.Printf("Timestamp:  %s\n", meta["timestamp"])
-	fmt.Printf("Added:      %s\n", resources["added"])
-	fmt.Printf("Changed:    %s\n", resources["changed"])
-	fmt.Printf("Destroyed:  %s\n", resources["destroyed"])
-	
+	fmt.Printf("Version:    %s\n", meta.Version)
+	fmt.Printf("Timestamp:  %s\n", meta.Timestamp)
+	fmt.Printf("Added:      %d\n", meta.Resources.Added)
+	fmt.Printf("Changed:    %d\n", meta.Resources.Changed)
+	fmt.Printf("Destroyed:  %d\n", meta.Resources.Destroyed)
+
 	fmt.Println("──────────────────────────────────────────────────────────────")
 	fmt.Println()
 	fmt.Println("Usage:")
 	fmt.Println("  cloudtm rollback --to vN        # Rollback to version")
+	fmt.Println("  cloudtm rollback --promote      # Keep the rollback as the new baseline")
 	fmt.Println("  cloudtm rollback --del          # Delete active rollback")
 	fmt.Println()
 }
@@ -243,7 +748,7 @@ func handleDeleteRollback(cloudtmDir string) {
 	isRollbackEmpty, err := helper.IsRollbackEmpty(cloudtmDir)
 	if err != nil {
 		fmt.Println("❌ Error reading rollback.json:", err)
-		os.Exit(1)
+		exit(1)
 	}
 
 	if isRollbackEmpty {
@@ -255,7 +760,7 @@ func handleDeleteRollback(cloudtmDir string) {
 	rollbackVersion, err := helper.GetRollbackVersion(cloudtmDir)
 	if err != nil {
 		fmt.Println("❌ Error getting rollback version:", err)
-		os.Exit(1)
+		exit(1)
 	}
 
 	fmt.Printf("✅ Found active rollback: %s\n", rollbackVersion)
@@ -268,39 +773,44 @@ func handleDeleteRollback(cloudtmDir string) {
 		fmt.Println("⚠️  Rollback directory not found, resetting rollback.json...")
 		if err := helper.UpdateRollbackVersion(cloudtmDir, ""); err != nil {
 			fmt.Println("❌ Error resetting rollback.json:", err)
-			os.Exit(1)
+			exit(1)
 		}
 		fmt.Println("✅ Reset rollback.json")
 		return
 	}
 
-	// Run terraform destroy in rollback directory
-	fmt.Println("\n🚀 Running 'terraform destroy --auto-approve' in rollback directory...")
-	destroyCmd := exec.Command("terraform", "destroy", "--auto-approve")
-	destroyCmd.Dir = rollbackDir
-	destroyCmd.Stdout = os.Stdout
-	destroyCmd.Stderr = os.Stderr
-	destroyCmd.Stdin = os.Stdin
-
-	if err := destroyCmd.Run(); err != nil {
+	// Run terraform destroy in rollback directory, with the variables the
+	// version was applied with
+	cwd, _ := os.Getwd()
+	tfArgs, cleanupInputs := replayInputs(localStore(cloudtmDir), cloudtmDir, rollbackVersion, cwd, rollbackDir)
+	fmt.Println("\n🚀 Running 'terraform destroy -json -auto-approve' in rollback directory...")
+	result, err := helper.RunTerraformJSON(rollbackDir, append([]string{"destroy", "-json", "-input=false", "-auto-approve"}, tfArgs...), os.Stdout)
+	cleanupInputs()
+	if err != nil {
 		fmt.Println("\n❌ Terraform destroy failed in rollback directory:", err)
 		fmt.Println("⚠️  Rollback directory preserved for investigation")
-		os.Exit(1)
+		recordFailedRollback(cloudtmDir, helper.EventRollbackDestroy, rollbackVersion, result, err)
+		exit(1)
 	}
 
 	fmt.Println("\n✅ Rollback resources destroyed successfully")
 
+	event := helper.NewHistoryEvent(helper.EventRollbackDestroy)
+	event.Source = rollbackVersion
+	event.Resources = helper.SummaryCounts(result.Summary)
+	recordEvent(cloudtmDir, event)
+
 	// Delete rollback directory
 	if err := os.RemoveAll(rollbackDir); err != nil {
 		fmt.Println("❌ Error deleting rollback directory:", err)
-		os.Exit(1)
+		exit(1)
 	}
 	fmt.Println("✅ Deleted rollback directory")
 
 	// Reset rollback.json
 	if err := helper.UpdateRollbackVersion(cloudtmDir, ""); err != nil {
 		fmt.Println("❌ Error resetting rollback.json:", err)
-		os.Exit(1)
+		exit(1)
 	}
 	fmt.Println("✅ Reset rollback.json")
 
@@ -308,8 +818,20 @@ func handleDeleteRollback(cloudtmDir string) {
 }
 
 func init() {
-	rollbackCmd.Flags().StringVar(&rollbackTo, "to", "", "Version to rollback to (e.g., v1, v2)")
+	rollbackCmd.Flags().StringVar(&rollbackTo, "to", "", "Version or tag to rollback to (e.g., v1, v2)")
+	rollbackCmd.Flags().BoolVar(&rollbackForce, "force", false, "Roll back even if the version's state lineage differs from the live state")
+	rollbackCmd.Flags().BoolVar(&rollbackInPlace, "in-place", false, "Restore the version's configuration into the project root and apply it against the live state")
+	rollbackCmd.Flags().BoolVar(&rollbackAutoApprove, "auto-approve", false, "Skip interactive approval of the in-place plan")
+	rollbackCmd.Flags().BoolVar(&rollbackDryRun, "dry-run", false, "Show what rolling back would change without touching infrastructure")
+	rollbackCmd.Flags().BoolVar(&promoteRollback, "promote", false, "Move the active rollback into the project root and record it as a new version")
 	rollbackCmd.Flags().BoolVar(&deleteRollback, "del", false, "Delete active rollback")
 	rollbackCmd.Flags().BoolVar(&deleteRollback, "delete", false, "Delete active rollback (alias for --del)")
 	rootCmd.AddCommand(rollbackCmd)
+
+	// Showing the status and dry runs never change .cloudtm, so they do not
+	// wait for (or block) a running apply
+	mutatesWhen[rollbackCmd] = func() bool {
+		showStatus := rollbackTo == "" && !deleteRollback && !rollbackInPlace && !rollbackDryRun && !promoteRollback
+		return !showStatus && !rollbackDryRun
+	}
 }
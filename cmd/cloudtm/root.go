@@ -18,16 +18,42 @@ Usage:
 
 The commands are:
     init         initialize cloudtm in current Terraform project
+    plan         show the changes apply would make (wrapper around Terraform plan)
     apply        apply infrastructure changes (wrapper around Terraform apply)
     snapshot     manually create a versioned snapshot of the current Terraform state
     list         list available state snapshots and versions
     rollback     restore infrastructure to a previous snapshot
+    diff         show changes between two versions
+    tag          name versions with tags
+    prune        delete old versions according to a retention policy
+    pin          protect versions from being pruned
+    fsck         verify the integrity of stored snapshots
+    verify       verify the hash chain over versions and the audit log
+    remote       show or set the remote snapshot store
+    push         upload local versions to the remote snapshot store
+    pull         download versions from the remote snapshot store
+    log          show the audit log of cloudtm operations
+    keygen       generate a snapshot encryption or signing key
+    rekey        encrypt, re-encrypt or decrypt all stored versions
+    force-unlock remove a stale .cloudtm lock
     version      print cloudtm CLI version
     help         show help for a command
 
 Use "cloudtm help <command>" for more information about a command.
 `,
 	// No Run function → ensures that just typing `cloudtm` shows this help text
+
+	// Every command is recorded in .cloudtm/audit.log and mutating commands
+	// hold the .cloudtm lock; commands that exit early call exit() so their
+	// entry is written and the lock released too
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		startAudit(cmd, args)
+		acquireLock(cmd)
+	},
+	PersistentPostRun: func(cmd *cobra.Command, args []string) {
+		finishAudit(cmd, 0)
+		releaseLock()
+	},
 }
 
 // Execute is called by main.go
@@ -0,0 +1,165 @@
+package cloudtm
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/raxkumar/cloudtm/helper"
+	"github.com/spf13/cobra"
+)
+
+var snapshotMessage string
+var snapshotCompressionFlag string
+
+var snapshotCmd = &cobra.Command{
+	Use:   "snapshot",
+	Short: "manually create a versioned snapshot of the current Terraform state",
+	Long: `Creates a new version from the current project without running Terraform.
+The project configuration, terraform.tfstate and .terraform.lock.hcl are stored
+as content-addressed objects referenced by .cloudtm/versions/vN/manifest.json,
+and metadata is written to .cloudtm/meta/vN.json.
+
+Useful for recording a baseline right after 'cloudtm init' on an existing stack,
+or after changing state outside of cloudtm (e.g. 'terraform import').
+
+Usage:
+    cloudtm snapshot
+    cloudtm snapshot -m "baseline after import"`,
+	Args:        cobra.NoArgs,
+	Annotations: map[string]string{annotationMutating: "true"},
+	Run: func(cmd *cobra.Command, args []string) {
+		// Step 1: Verify CloudTimeMachine directories
+		cwd, _ := os.Getwd()
+		cloudtmDir := filepath.Join(cwd, ".cloudtm")
+		versionDir := filepath.Join(cloudtmDir, "versions")
+		metaDir := filepath.Join(cloudtmDir, "meta")
+
+		if _, err := os.Stat(cloudtmDir); os.IsNotExist(err) {
+			fmt.Println("❌ CloudTimeMachine not initialized. Run: cloudtm init")
+			exit(1)
+		}
+		os.MkdirAll(versionDir, 0755)
+		os.MkdirAll(metaDir, 0755)
+
+		compression := snapshotCompression(cloudtmDir, snapshotCompressionFlag)
+		if err := helper.ValidateCompression(compression); err != nil {
+			fmt.Println("❌", err)
+			exit(1)
+		}
+
+		// Step 2: Determine whether the captured state has deployed resources
+		deployed := false
+		isEmpty, err := helper.IsStateEmpty(cwd)
+		if err == nil {
+			deployed = !isEmpty
+		} else if os.IsNotExist(err) {
+			fmt.Println("⚠️  No terraform.tfstate found — snapshot will contain configuration only.")
+		} else {
+			fmt.Println("⚠️  Warning: Could not read terraform.tfstate:", err)
+		}
+
+		stateInfo := liveStateInfo(cwd)
+		warnLineageChange(cloudtmDir, stateInfo)
+
+		// Step 3: Copy the project into a new version
+		nextVersion, err := helper.NextVersion(cloudtmDir)
+		if err != nil {
+			fmt.Println("❌ Error allocating version number:", err)
+			exit(1)
+		}
+
+		// The version is staged and moved into versions/ once complete
+		staged := helper.StagingStore(localStore(cloudtmDir), nextVersion)
+		snapshot, err := helper.CreateSnapshot(staged, cwd, nextVersion, compression)
+		if err != nil {
+			fmt.Println("❌ Failed to copy project files:", err)
+			exit(1)
+		}
+
+		// Step 4: Write metadata (no Terraform run, so no resource changes)
+		meta := helper.NewMetadata(nextVersion)
+		meta.Message = strings.TrimSpace(snapshotMessage)
+		meta.State = stateInfo
+		sealVersion(staged, cloudtmDir, meta)
+		if err := helper.CommitStagedVersion(cloudtmDir, nextVersion); err != nil {
+			fmt.Println("❌ Failed to move version into place:", err)
+			exit(1)
+		}
+		metaDest, err := helper.SaveMetadata(cloudtmDir, meta)
+		if err != nil {
+			fmt.Println("❌ Failed to write metadata file:", err)
+			exit(1)
+		}
+
+		// Step 5: Update current.json
+		if err := helper.UpdateCurrentVersion(cloudtmDir, nextVersion, deployed); err != nil {
+			fmt.Println("❌ Failed to update current.json:", err)
+			exit(1)
+		}
+
+		event := helper.NewHistoryEvent(helper.EventSnapshot)
+		event.Version = nextVersion
+		event.Message = meta.Message
+		recordEvent(cloudtmDir, event)
+
+		fmt.Printf("📦 Snapshot created: %s\n", nextVersion)
+		fmt.Printf("🗂  Saved configs: %s\n", snapshot)
+		fmt.Printf("🧾 Metadata: %s\n", metaDest)
+		fmt.Printf("✅ Updated current version to: %s\n", nextVersion)
+	},
+}
+
+// snapshotCompression returns the compression codec for new versions: the flag
+// value when given, otherwise the one configured in config.json
+func snapshotCompression(cloudtmDir, flag string) string {
+	if flag != "" {
+		return flag
+	}
+	cfg, err := helper.LoadConfig(cloudtmDir)
+	if err != nil {
+		fmt.Println("⚠️  Warning: Could not read config.json:", err)
+		return ""
+	}
+	return cfg.Compression
+}
+
+// liveStateInfo reads the lineage and serial of the project's local state,
+// warning instead of failing when it cannot be read
+func liveStateInfo(cwd string) *helper.StateInfo {
+	info, err := helper.ReadStateInfo(cwd)
+	if err != nil {
+		fmt.Println("⚠️  Warning: Could not read state lineage:", err)
+		return nil
+	}
+	return info
+}
+
+// warnLineageChange warns when the live state's lineage differs from the one
+// recorded by the newest version, which usually means the project was
+// re-initialized against a different state (e.g. 'terraform init -reconfigure')
+func warnLineageChange(cloudtmDir string, live *helper.StateInfo) {
+	if live == nil {
+		return
+	}
+	metas, _, err := helper.LoadAllMetadata(cloudtmDir)
+	if err != nil {
+		return
+	}
+	latest := helper.LatestStateInfo(metas)
+	if latest == nil {
+		return
+	}
+	if err := helper.CheckLineage(latest.Version, latest.State, live); err != nil {
+		fmt.Printf("⚠️  Warning: State lineage changed since %s (%s → %s)\n", latest.Version, latest.State.Lineage, live.Lineage)
+		fmt.Println("⚠️  This state is not a continuation of the previous version's state.")
+		fmt.Println("💡 Check that 'terraform init -reconfigure' or a backend change did not point the project at a different stack.")
+	}
+}
+
+func init() {
+	snapshotCmd.Flags().StringVarP(&snapshotMessage, "message", "m", "", "Message describing the snapshot")
+	snapshotCmd.Flags().StringVar(&snapshotCompressionFlag, "compression", "", "Store the version as a compressed archive: none, gzip or zstd (default from config.json)")
+	rootCmd.AddCommand(snapshotCmd)
+}
@@ -0,0 +1,190 @@
+package cloudtm
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/raxkumar/cloudtm/helper"
+	"github.com/spf13/cobra"
+)
+
+var (
+	tagPin    bool
+	tagDelete bool
+	tagForce  bool
+)
+
+var tagCmd = &cobra.Command{
+	Use:   "tag [version] [name...]",
+	Short: "name versions with tags",
+	Long: `Names a version with one or more tags, stored in the version's metadata.
+Tags are accepted wherever a version is: 'rollback --to', 'list', 'pin',
+'push' and 'pull'. A tag names a single version; use --force to move it.
+Pinned tags (--pin) protect the version from 'cloudtm prune'.
+
+Usage:
+    cloudtm tag                              # List all tags
+    cloudtm tag v7 pre-migration             # Tag a version
+    cloudtm tag v7 pre-migration --pin       # Tag and protect from pruning
+    cloudtm tag v9 pre-migration --force     # Move a tag to another version
+    cloudtm tag --delete pre-migration       # Remove a tag`,
+	Annotations: map[string]string{annotationMutating: "true"},
+	Run: func(cmd *cobra.Command, args []string) {
+		cwd, _ := os.Getwd()
+		cloudtmDir := filepath.Join(cwd, ".cloudtm")
+
+		if _, err := os.Stat(cloudtmDir); os.IsNotExist(err) {
+			fmt.Println("❌ CloudTimeMachine not initialized. Run: cloudtm init")
+			exit(1)
+		}
+
+		metas, badMeta, err := helper.LoadAllMetadata(cloudtmDir)
+		if err != nil {
+			fmt.Println("❌ Error reading meta directory:", err)
+			exit(1)
+		}
+
+		switch {
+		case tagDelete:
+			if len(args) == 0 {
+				fmt.Println("❌ Specify the tags to delete")
+				exit(1)
+			}
+			deleteTags(cloudtmDir, metas, args)
+		case len(args) == 0:
+			listTags(metas)
+			reportMalformedMetadata(badMeta)
+		case len(args) == 1:
+			fmt.Println("❌ Specify at least one tag name: cloudtm tag <version> <name>")
+			exit(1)
+		default:
+			addTags(cloudtmDir, metas, args[0], args[1:])
+		}
+	},
+}
+
+// addTags tags the version referenced by ref, moving tags held by other
+// versions only when --force is given
+func addTags(cloudtmDir string, metas []*helper.Metadata, ref string, names []string) {
+	version := resolveVersion(localStore(cloudtmDir), ref)
+
+	var target *helper.Metadata
+	for _, meta := range metas {
+		if meta.Version == version {
+			target = meta
+		}
+	}
+	if target == nil {
+		fmt.Printf("❌ Version '%s' does not exist\n", version)
+		exit(1)
+	}
+
+	for _, name := range names {
+		if err := helper.ValidateTagName(name); err != nil {
+			fmt.Println("❌", err)
+			exit(1)
+		}
+		holder := helper.FindTag(metas, name)
+		if holder == nil || holder == target {
+			continue
+		}
+		if !tagForce {
+			fmt.Printf("❌ Tag '%s' already names %s. Use --force to move it.\n", name, holder.Version)
+			exit(1)
+		}
+		holder.RemoveTag(name)
+		if _, err := helper.SaveMetadata(cloudtmDir, holder); err != nil {
+			fmt.Printf("❌ Error writing metadata of '%s': %v\n", holder.Version, err)
+			exit(1)
+		}
+		fmt.Printf("↪️  Moved tag '%s' from %s\n", name, holder.Version)
+	}
+
+	for _, name := range names {
+		target.SetTag(name, tagPin)
+	}
+	if _, err := helper.SaveMetadata(cloudtmDir, target); err != nil {
+		fmt.Printf("❌ Error writing metadata of '%s': %v\n", target.Version, err)
+		exit(1)
+	}
+
+	pinned := ""
+	if tagPin {
+		pinned = " (pinned)"
+	}
+	fmt.Printf("🏷️  Tagged %s: %s%s\n", target.Version, strings.Join(names, ", "), pinned)
+}
+
+func deleteTags(cloudtmDir string, metas []*helper.Metadata, names []string) {
+	for _, name := range names {
+		holder := helper.FindTag(metas, name)
+		if holder == nil {
+			fmt.Printf("⚠️  Tag '%s' does not exist\n", name)
+			continue
+		}
+		holder.RemoveTag(name)
+		if _, err := helper.SaveMetadata(cloudtmDir, holder); err != nil {
+			fmt.Printf("❌ Error writing metadata of '%s': %v\n", holder.Version, err)
+			exit(1)
+		}
+		fmt.Printf("✅ Deleted tag '%s' from %s\n", name, holder.Version)
+	}
+}
+
+func listTags(metas []*helper.Metadata) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "Tag\tVersion\tPinned")
+	fmt.Fprintln(w, "───\t───────\t──────")
+	count := 0
+	for i := len(metas) - 1; i >= 0; i-- {
+		for _, t := range metas[i].Tags {
+			pinned := "-"
+			if t.Pinned {
+				pinned = "yes"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\n", t.Name, metas[i].Version, pinned)
+			count++
+		}
+	}
+	if count == 0 {
+		fmt.Println("ℹ️  No tags. Tag a version with: cloudtm tag <version> <name>")
+		return
+	}
+	w.Flush()
+}
+
+// formatTags renders a version's tags for tables, marking pinned ones
+func formatTags(meta *helper.Metadata) string {
+	if len(meta.Tags) == 0 {
+		return "-"
+	}
+	names := make([]string, len(meta.Tags))
+	for i, t := range meta.Tags {
+		names[i] = t.Name
+		if t.Pinned {
+			names[i] += " (pinned)"
+		}
+	}
+	return strings.Join(names, ", ")
+}
+
+// resolveVersion turns a version name or tag into a version name, exiting
+// when the tag is unknown
+func resolveVersion(store helper.SnapshotStore, ref string) string {
+	version, err := helper.ResolveVersion(store, ref)
+	if err != nil {
+		fmt.Printf("❌ Error: %v\n", err)
+		exit(1)
+	}
+	return version
+}
+
+func init() {
+	tagCmd.Flags().BoolVar(&tagPin, "pin", false, "Pin the tags so the version is never pruned")
+	tagCmd.Flags().BoolVar(&tagDelete, "delete", false, "Delete the given tags")
+	tagCmd.Flags().BoolVarP(&tagForce, "force", "f", false, "Move tags that already name another version")
+	rootCmd.AddCommand(tagCmd)
+}
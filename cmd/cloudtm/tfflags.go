@@ -0,0 +1,145 @@
+package cloudtm
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strconv"
+
+	"github.com/raxkumar/cloudtm/helper"
+	"github.com/spf13/cobra"
+)
+
+// terraformFlags are the common Terraform options of the commands that wrap
+// Terraform. Anything else is passed after "--", e.g.
+// 'cloudtm apply -- -lock-timeout=5m'.
+type terraformFlags struct {
+	varFiles    []string
+	vars        []string
+	targets     []string
+	replace     []string
+	parallelism int
+	refresh     bool
+}
+
+func (f *terraformFlags) register(cmd *cobra.Command) {
+	cmd.Flags().StringArrayVar(&f.varFiles, "var-file", nil, "Load variable values from a .tfvars file (repeatable)")
+	cmd.Flags().StringArrayVar(&f.vars, "var", nil, "Set a variable, e.g. --var 'region=eu-west-1' (repeatable)")
+	cmd.Flags().StringArrayVar(&f.targets, "target", nil, "Limit the operation to a resource address (repeatable)")
+	cmd.Flags().StringArrayVar(&f.replace, "replace", nil, "Force replacement of a resource address (repeatable)")
+	cmd.Flags().IntVar(&f.parallelism, "parallelism", 0, "Limit the number of concurrent operations (default 10)")
+	cmd.Flags().BoolVar(&f.refresh, "refresh", true, "Refresh state before planning (--refresh=false to skip)")
+}
+
+// args returns the Terraform arguments for the flags followed by the
+// arguments given after "--", normalized to the "-name=value" form
+func (f *terraformFlags) args(cmd *cobra.Command, args []string) []string {
+	var tfArgs []string
+	for _, file := range f.varFiles {
+		tfArgs = append(tfArgs, "-var-file="+file)
+	}
+	for _, v := range f.vars {
+		tfArgs = append(tfArgs, "-var="+v)
+	}
+	for _, addr := range f.targets {
+		tfArgs = append(tfArgs, "-target="+addr)
+	}
+	if len(f.replace) > 0 && activeBinary != nil && !activeBinary.SupportsReplace() {
+		fmt.Printf("❌ %s does not support -replace; use 'terraform taint' or upgrade\n", activeBinary)
+		exit(1)
+	}
+	for _, addr := range f.replace {
+		tfArgs = append(tfArgs, "-replace="+addr)
+	}
+	if f.parallelism > 0 {
+		tfArgs = append(tfArgs, "-parallelism="+strconv.Itoa(f.parallelism))
+	}
+	if !f.refresh {
+		tfArgs = append(tfArgs, "-refresh=false")
+	}
+	if dash := cmd.ArgsLenAtDash(); dash >= 0 {
+		tfArgs = append(tfArgs, args[dash:]...)
+	}
+	return helper.NormalizeArgs(tfArgs)
+}
+
+// terraformPassthroughArgs accepts positional arguments only after "--",
+// where they are forwarded to Terraform
+func terraformPassthroughArgs(cmd *cobra.Command, args []string) error {
+	dash := cmd.ArgsLenAtDash()
+	if dash < 0 {
+		dash = len(args)
+	}
+	if dash > 0 {
+		return fmt.Errorf("unexpected argument %q (pass Terraform flags after --, e.g. cloudtm %s -- -lock-timeout=5m)", args[0], cmd.Name())
+	}
+	return nil
+}
+
+// replayInputs returns the Terraform arguments that give a run in runDir the
+// variables version was applied with: the recorded -var-file arguments,
+// followed by a var-file with the captured effective values (including those
+// given with -var), which take precedence. The var-file is written to
+// varFileDir, usually .cloudtm; cleanup removes it, and one left behind by an
+// exit is removed with the other temporary files by the next mutating command.
+func replayInputs(store helper.SnapshotStore, varFileDir, version, cwd, runDir string) (args []string, cleanup func()) {
+	cleanup = func() {}
+	if meta := versionMetadata(store, version); meta != nil && meta.Inputs != nil {
+		var missing []string
+		args, missing = meta.Inputs.ReplayArgs(cwd, runDir)
+		for _, file := range missing {
+			fmt.Printf("⚠️  Warning: var-file '%s' recorded by %s not found — skipping it\n", file, version)
+		}
+		if len(args) > 0 {
+			fmt.Printf("🔁 Replaying %d variable argument(s) recorded by %s\n", len(args), version)
+		}
+	}
+
+	vars, err := helper.LoadVariables(store, version)
+	if errors.Is(err, fs.ErrNotExist) {
+		return args, cleanup
+	}
+	if err != nil {
+		fmt.Printf("⚠️  Warning: Could not read the variables of '%s': %v\n", version, err)
+		return args, cleanup
+	}
+	data, redacted, err := vars.TFVars()
+	if err == nil {
+		var path string
+		path, err = writeReplayVarFile(varFileDir, data)
+		if err == nil {
+			args = append(args, "-var-file="+path)
+			cleanup = func() { os.Remove(path) }
+		}
+	}
+	if err != nil {
+		fmt.Printf("⚠️  Warning: Could not replay the variables of '%s': %v\n", version, err)
+		return args, cleanup
+	}
+
+	fmt.Printf("🔁 Replaying %d variable value(s) captured with %s\n", len(vars.Variables)-len(redacted), version)
+	for _, name := range redacted {
+		fmt.Printf("⚠️  Sensitive variable '%s' was not stored (versions are not encrypted); set it with TF_VAR_%s or a var-file\n", name, name)
+	}
+	return args, cleanup
+}
+
+// writeReplayVarFile writes captured variable values to a temporary
+// .tfvars.json file in dir readable only by the owner
+func writeReplayVarFile(dir string, data []byte) (string, error) {
+	f, err := os.CreateTemp(dir, ".replay.tmp-*.tfvars.json")
+	if err != nil {
+		return "", err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return "", err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+	return f.Name(), nil
+}
@@ -0,0 +1,62 @@
+package cloudtm
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/raxkumar/cloudtm/helper"
+)
+
+// recordEvent appends an event to .cloudtm/history.jsonl, warning on failure
+func recordEvent(cloudtmDir string, event *helper.HistoryEvent) {
+	if err := helper.AppendHistory(cloudtmDir, event); err != nil {
+		fmt.Println("⚠️  Warning: Failed to record history:", err)
+	}
+}
+
+// showTimeline prints every recorded event (applies, destroys, rollbacks
+// and deletions) merged with the versions, newest first
+func showTimeline(cloudtmDir string, versions []*helper.Metadata) {
+	history, err := helper.ReadHistory(cloudtmDir)
+	if err != nil {
+		fmt.Println("⚠️  Warning: Could not read history:", err)
+	}
+	timeline := helper.Timeline(history, versions)
+
+	fmt.Println("\n🕒 CloudTimeMachine Timeline")
+	fmt.Println("──────────────────────────────────────────────────────────────")
+	if len(timeline) == 0 {
+		fmt.Println("ℹ️  No history recorded yet")
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "Timestamp\tEvent\tVersion\tSource\tAdded\tChanged\tDestroyed\tMessage")
+	fmt.Fprintln(w, "─────────────────────\t─────\t───────\t──────\t─────\t───────\t─────────\t───────")
+	for i := len(timeline) - 1; i >= 0; i-- {
+		e := timeline[i]
+		added, changed, destroyed := "-", "-", "-"
+		if e.Resources != nil {
+			added = fmt.Sprint(e.Resources.Added)
+			changed = fmt.Sprint(e.Resources.Changed)
+			destroyed = fmt.Sprint(e.Resources.Destroyed)
+		}
+		event := e.Event
+		if e.Status != "" {
+			event += " (" + e.Status + ")"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			e.Timestamp, event, orDash(e.Version), orDash(e.Source),
+			added, changed, destroyed, orDash(e.Message))
+	}
+	w.Flush()
+	fmt.Println("──────────────────────────────────────────────────────────────")
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -0,0 +1,168 @@
+package cloudtm
+
+import (
+	"crypto/ed25519"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/raxkumar/cloudtm/helper"
+	"github.com/spf13/cobra"
+)
+
+var verifyRemote string
+var verifyPublicKey string
+
+var verifyCmd = &cobra.Command{
+	Use:   "verify",
+	Short: "verify the hash chain over versions and the audit log",
+	Long: `Checks that the version history was not edited after the fact.
+
+Every version's metadata records a hash of its files and the hash of the
+previous version's link, forming a chain. verify walks meta/ and versions/
+and reports:
+- versions whose files or metadata were modified
+- broken links and versions missing from the chain
+- snapshot files without metadata
+- invalid signatures, when a public key is available
+- modified or removed entries of .cloudtm/audit.log
+- a newest version that is missing or disagrees with current.json and the
+  audit log
+
+Versions deleted by 'cloudtm prune' are reported as warnings; the deletions
+are taken from the audit log, whose entries are chained too, so they cannot
+be made up. The audit log and current.json are only checked locally.
+
+Signatures are checked with --public-key, or with the public half of the
+configured signing key (see 'cloudtm keygen --signing').
+
+Usage:
+    cloudtm verify
+    cloudtm verify --public-key ~/.cloudtm/sign.key.pub
+    cloudtm verify --remote s3://bucket/app`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		// Step 1: Verify CloudTimeMachine is initialized
+		cwd, _ := os.Getwd()
+		cloudtmDir := filepath.Join(cwd, ".cloudtm")
+
+		if _, err := os.Stat(cloudtmDir); os.IsNotExist(err) {
+			fmt.Println("❌ CloudTimeMachine not initialized. Run: cloudtm init")
+			exit(1)
+		}
+
+		store := localStore(cloudtmDir)
+		if verifyRemote != "" {
+			store = openRemote(cloudtmDir, verifyRemote)
+		}
+		publicKey := verifyingKey(cloudtmDir)
+		fmt.Printf("🔍 Verifying version history in %s...\n", store)
+
+		// Step 2: Read the metadata of every version
+		errors, warnings := 0, 0
+		metaVersions, err := helper.StoreVersions(store)
+		if err != nil {
+			fmt.Println("❌ Error listing metadata:", err)
+			exit(1)
+		}
+		hasMeta := make(map[string]bool)
+		var metas []*helper.Metadata
+		for _, v := range metaVersions {
+			meta, err := helper.ReadStoreMetadata(store, v)
+			if err != nil {
+				fmt.Printf("❌ %s: unreadable metadata: %v\n", v, err)
+				errors++
+				continue
+			}
+			hasMeta[v] = true
+			metas = append(metas, meta)
+		}
+
+		snapshotVersions, err := helper.SnapshotVersions(store)
+		if err != nil {
+			fmt.Println("❌ Error listing versions:", err)
+			exit(1)
+		}
+		for _, v := range snapshotVersions {
+			if !hasMeta[v] {
+				fmt.Printf("❌ %s: snapshot files without metadata (meta/%s.json)\n", v, v)
+				errors++
+			}
+		}
+
+		// Step 3: Read the audit log, the record of which versions were
+		// pruned and which one is current
+		var entries []helper.AuditEntry
+		if verifyRemote == "" {
+			entries, err = helper.ReadAudit(cloudtmDir)
+			if err != nil {
+				fmt.Println("❌ audit.log:", err)
+				errors++
+			}
+			for _, p := range helper.VerifyAudit(entries) {
+				fmt.Printf("❌ audit.log %s\n", p)
+				errors++
+			}
+		}
+
+		// Step 4: Walk the hash chain
+		problems := helper.VerifyChain(store, metas, helper.PrunedVersions(entries), publicKey)
+		if verifyRemote == "" {
+			current, _, err := helper.GetCurrentVersion(cloudtmDir)
+			if err != nil {
+				fmt.Println("❌ Error reading current version:", err)
+				errors++
+			}
+			problems = append(problems, helper.VerifyChainHead(metas, current, entries)...)
+		}
+		for _, p := range problems {
+			if p.Warning {
+				fmt.Printf("⚠️  %s\n", p.Error())
+				warnings++
+			} else {
+				fmt.Printf("❌ %s\n", p.Error())
+				errors++
+			}
+		}
+		if latest := helper.LatestLink(metas); latest != nil {
+			fmt.Printf("🔗 Chain head: %s (%s)\n", latest.Version, latest.Chain.Hash[:12])
+		}
+		if publicKey == nil {
+			fmt.Println("ℹ️  Signatures not checked (no public key; use --public-key)")
+		}
+		if verifyRemote == "" {
+			fmt.Printf("📜 Audit log: %d entries checked\n", len(entries))
+		}
+
+		// Step 5: Summary
+		fmt.Println("──────────────────────────────────────────────────────────────")
+		if errors > 0 {
+			fmt.Printf("❌ %d problem(s) and %d warning(s) in %d version(s)\n", errors, warnings, len(metas))
+			exit(1)
+		}
+		fmt.Printf("✅ History of %d version(s) verified (%d warning(s))\n", len(metas), warnings)
+	},
+}
+
+// verifyingKey returns the public key signatures are checked with: from
+// --public-key, otherwise derived from the configured signing key, or nil
+func verifyingKey(cloudtmDir string) ed25519.PublicKey {
+	if verifyPublicKey != "" {
+		key, err := helper.ReadPublicKeyFile(verifyPublicKey)
+		if err != nil {
+			fmt.Println("❌ Error reading public key:", err)
+			exit(1)
+		}
+		return key
+	}
+	if private := signingKey(cloudtmDir); private != nil {
+		return private.Public().(ed25519.PublicKey)
+	}
+	return nil
+}
+
+func init() {
+	verifyCmd.Flags().StringVar(&verifyRemote, "remote", "", "Verify a remote store instead of the local .cloudtm directory")
+	verifyCmd.Flags().StringVar(&verifyPublicKey, "public-key", "", "Public key file to check version signatures with")
+	rootCmd.AddCommand(verifyCmd)
+}
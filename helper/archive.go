@@ -0,0 +1,154 @@
+package helper
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Supported snapshot compression codecs
+const (
+	CompressionNone = "none"
+	CompressionGzip = "gzip"
+	CompressionZstd = "zstd"
+)
+
+// archiveExtensions maps a compression codec to its archive file extension
+var archiveExtensions = map[string]string{
+	CompressionGzip: ".tar.gz",
+	CompressionZstd: ".tar.zst",
+}
+
+// ValidateCompression checks that the codec is supported
+func ValidateCompression(compression string) error {
+	if compression == "" || compression == CompressionNone {
+		return nil
+	}
+	if _, ok := archiveExtensions[compression]; !ok {
+		return fmt.Errorf("unsupported compression %q (use none, gzip or zstd)", compression)
+	}
+	return nil
+}
+
+// archiveKey returns the store key of a version archive compressed with the codec
+func archiveKey(version, compression string) string {
+	return "versions/" + version + "/snapshot" + archiveExtensions[compression]
+}
+
+func compressWriter(w io.Writer, compression string) (io.WriteCloser, error) {
+	switch compression {
+	case CompressionGzip:
+		return gzip.NewWriterLevel(w, gzip.BestCompression)
+	case CompressionZstd:
+		return zstd.NewWriter(w, zstd.WithEncoderLevel(zstd.SpeedBetterCompression))
+	}
+	return nil, ValidateCompression(compression)
+}
+
+func decompressReader(r io.Reader, compression string) (io.ReadCloser, error) {
+	switch compression {
+	case CompressionGzip:
+		return gzip.NewReader(r)
+	case CompressionZstd:
+		dec, err := zstd.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		return dec.IOReadCloser(), nil
+	}
+	return nil, ValidateCompression(compression)
+}
+
+// createArchive stores the project directory as a single compressed tar
+// archive at versions/<version>/snapshot.tar.<ext>
+func createArchive(store SnapshotStore, projectDir, version, compression string) (*SnapshotResult, error) {
+	var buf bytes.Buffer
+	cw, err := compressWriter(&buf, compression)
+	if err != nil {
+		return nil, err
+	}
+	tw := tar.NewWriter(cw)
+
+	result := &SnapshotResult{Format: compression + " archive"}
+	err = WalkProjectFiles(projectDir, SnapshotExcludeDirs, SnapshotExcludeFiles, SnapshotExcludePatterns, func(src, relPath string, info os.FileInfo) error {
+		data, err := os.ReadFile(src)
+		if err != nil {
+			return err
+		}
+
+		header := &tar.Header{
+			Name:    filepath.ToSlash(relPath),
+			Mode:    int64(info.Mode().Perm()),
+			Size:    int64(len(data)),
+			ModTime: info.ModTime().UTC().Truncate(time.Second),
+			Format:  tar.FormatPAX,
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		if _, err := tw.Write(data); err != nil {
+			return err
+		}
+
+		result.Files++
+		result.Bytes += int64(len(data))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	if err := cw.Close(); err != nil {
+		return nil, err
+	}
+
+	result.StoredBytes = int64(buf.Len())
+	return result, store.Put(archiveKey(version, compression), &buf)
+}
+
+// readArchive extracts every file of a version archive into memory
+func readArchive(r io.Reader, compression string) ([]SnapshotFile, map[string][]byte, error) {
+	dr, err := decompressReader(r, compression)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer dr.Close()
+
+	var files []SnapshotFile
+	contents := make(map[string][]byte)
+	tr := tar.NewReader(dr)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, nil, err
+		}
+		files = append(files, SnapshotFile{
+			Path:   header.Name,
+			SHA256: HashBytes(data),
+			Size:   int64(len(data)),
+			Mode:   os.FileMode(header.Mode).Perm(),
+		})
+		contents[header.Name] = data
+	}
+	return files, contents, nil
+}
@@ -0,0 +1,72 @@
+package helper
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// tempMarker is part of the name of every temporary file written by
+// writeAtomic; leftovers of interrupted writes are found by it
+const tempMarker = ".tmp-"
+
+// WriteFileAtomic writes data to a temporary file next to path, syncs it to
+// disk and renames it over path, so readers (and a run after a crash) see
+// either the old or the complete new content, never a partial file
+func WriteFileAtomic(path string, data []byte, perm os.FileMode) error {
+	return writeAtomic(path, perm, func(w io.Writer) error {
+		_, err := w.Write(data)
+		return err
+	})
+}
+
+// writeAtomic is WriteFileAtomic for content produced by write
+func writeAtomic(path string, perm os.FileMode, write func(w io.Writer) error) error {
+	dir := filepath.Dir(path)
+	f, err := os.CreateTemp(dir, "."+filepath.Base(path)+tempMarker+"*")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+	fail := func(err error) error {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+
+	if err := write(f); err != nil {
+		return fail(err)
+	}
+	if err := f.Sync(); err != nil {
+		return fail(err)
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Chmod(tmp, perm); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	syncDir(dir)
+	return nil
+}
+
+// syncDir flushes a directory entry change (a rename) to disk. Not every
+// platform can sync directories, so errors are ignored.
+func syncDir(dir string) {
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
+}
+
+// IsTempFile reports whether a file name is a temporary file of writeAtomic
+func IsTempFile(name string) bool {
+	return strings.HasPrefix(name, ".") && strings.Contains(name, tempMarker)
+}
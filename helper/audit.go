@@ -0,0 +1,287 @@
+package helper
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// AuditFileName is the append-only log of cloudtm invocations kept in .cloudtm/
+const AuditFileName = "audit.log"
+
+// AuditEntry records a single cloudtm invocation
+type AuditEntry struct {
+	Command          string   `json:"command"`
+	Args             []string `json:"args"`
+	User             string   `json:"user"`
+	Host             string   `json:"host"`
+	GitCommit        string   `json:"gitCommit,omitempty"`
+	TerraformVersion string   `json:"terraformVersion,omitempty"`
+	TerraformBinary  string   `json:"terraformBinary,omitempty"` // "terraform" or "tofu"
+	Start            string   `json:"start"`
+	End              string   `json:"end"`
+	ExitStatus       int      `json:"exitStatus"`
+	Version          string   `json:"version,omitempty"` // current version after the command
+	Pruned           []string `json:"pruned,omitempty"`  // versions deleted by cloudtm prune
+
+	// Entries form a hash chain: PrevHash is the Hash of the previous entry
+	// and Hash covers every other field, so editing or removing an entry
+	// breaks the chain
+	PrevHash string `json:"prevHash,omitempty"`
+	Hash     string `json:"hash,omitempty"`
+}
+
+// ComputeHash returns the hash of the entry's fields other than Hash
+func (e *AuditEntry) ComputeHash() string {
+	unhashed := *e
+	unhashed.Hash = ""
+	data, _ := json.Marshal(&unhashed)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// StartTime parses the entry's start time
+func (e *AuditEntry) StartTime() (time.Time, error) {
+	return time.Parse(time.RFC3339Nano, e.Start)
+}
+
+// Duration returns how long the command ran, or 0 when unknown
+func (e *AuditEntry) Duration() time.Duration {
+	start, err := e.StartTime()
+	if err != nil {
+		return 0
+	}
+	end, err := time.Parse(time.RFC3339Nano, e.End)
+	if err != nil {
+		return 0
+	}
+	return end.Sub(start)
+}
+
+// AppendAudit links an entry to the last one in audit.log and appends it.
+// Entries are only ever appended, never rewritten. The file is locked while
+// the last entry is read and the new one written, so concurrent commands
+// (read-only ones do not hold the .cloudtm lock) cannot fork the chain.
+func AppendAudit(cloudtmDir string, entry *AuditEntry) error {
+	f, err := os.OpenFile(filepath.Join(cloudtmDir, AuditFileName), os.O_RDWR|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := lockFile(f); err != nil {
+		return fmt.Errorf("locking %s: %w", AuditFileName, err)
+	}
+	defer unlockFile(f)
+
+	prevHash, err := lastAuditHash(f)
+	if err != nil {
+		return err
+	}
+	entry.PrevHash = prevHash
+	entry.Hash = entry.ComputeHash()
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	return f.Sync()
+}
+
+// lastAuditHash returns the hash of the last entry of the audit log, or ""
+// when the log is empty
+func lastAuditHash(f *os.File) (string, error) {
+	last, err := lastLine(f)
+	if err != nil || len(last) == 0 {
+		return "", err
+	}
+	var entry AuditEntry
+	if err := json.Unmarshal(last, &entry); err != nil {
+		return "", fmt.Errorf("%s: last entry: %w", AuditFileName, err)
+	}
+	return entry.Hash, nil
+}
+
+// lastLine returns the last non-empty line of f, reading backwards from the
+// end in chunks until the newline before it is found, however long it is
+func lastLine(f *os.File) ([]byte, error) {
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	const chunkSize = 4096
+	var tail []byte
+	for pos := info.Size(); pos > 0; {
+		n := int64(chunkSize)
+		if pos < n {
+			n = pos
+		}
+		pos -= n
+		chunk := make([]byte, n, n+int64(len(tail)))
+		if _, err := f.ReadAt(chunk, pos); err != nil {
+			return nil, err
+		}
+		tail = append(chunk, tail...)
+
+		trimmed := bytes.TrimRight(tail, "\n")
+		if i := bytes.LastIndexByte(trimmed, '\n'); i >= 0 {
+			return trimmed[i+1:], nil
+		}
+	}
+	return bytes.TrimRight(tail, "\n"), nil
+}
+
+// VerifyAudit checks the hash chain of audit log entries and returns a
+// description of every entry that was modified or whose predecessor was
+// removed. Entries written before hash chaining are skipped.
+func VerifyAudit(entries []AuditEntry) []string {
+	var problems []string
+	prevHash := ""
+	chained := false
+	for i := range entries {
+		e := &entries[i]
+		n := i + 1
+		if e.Hash == "" {
+			if chained {
+				problems = append(problems, fmt.Sprintf("entry %d (%s at %s): missing hash", n, e.Command, e.Start))
+			}
+			prevHash = ""
+			continue
+		}
+		if e.ComputeHash() != e.Hash {
+			problems = append(problems, fmt.Sprintf("entry %d (%s at %s): modified", n, e.Command, e.Start))
+		}
+		if chained && e.PrevHash != prevHash {
+			problems = append(problems, fmt.Sprintf("entry %d (%s at %s): broken link, an earlier entry was removed or changed", n, e.Command, e.Start))
+		}
+		chained = true
+		prevHash = e.Hash
+	}
+	return problems
+}
+
+// PrunedVersions returns the versions deleted by prune according to the
+// hash-chained entries of the audit log. Entries without a hash are ignored,
+// so evidence of a deletion cannot be added without breaking the chain.
+func PrunedVersions(entries []AuditEntry) map[string]bool {
+	pruned := make(map[string]bool)
+	for _, e := range entries {
+		if e.Hash == "" {
+			continue
+		}
+		for _, v := range e.Pruned {
+			pruned[v] = true
+		}
+	}
+	return pruned
+}
+
+// ReadAudit returns the entries of audit.log, oldest first. A missing file is
+// an empty log.
+func ReadAudit(cloudtmDir string) ([]AuditEntry, error) {
+	f, err := os.Open(filepath.Join(cloudtmDir, AuditFileName))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []AuditEntry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for n := 1; scanner.Scan(); n++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var entry AuditEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return entries, fmt.Errorf("%s line %d: %w", AuditFileName, n, err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, scanner.Err()
+}
+
+// AuditFilter selects audit entries. Zero fields match everything.
+type AuditFilter struct {
+	Command string
+	User    string
+	Since   time.Time
+	Until   time.Time
+}
+
+// Match reports whether the entry passes the filter
+func (f AuditFilter) Match(e *AuditEntry) bool {
+	if f.Command != "" && e.Command != f.Command && !strings.HasPrefix(e.Command, f.Command+" ") {
+		return false
+	}
+	if f.User != "" && e.User != f.User {
+		return false
+	}
+	if !f.Since.IsZero() || !f.Until.IsZero() {
+		start, err := e.StartTime()
+		if err != nil {
+			return false
+		}
+		if !f.Since.IsZero() && start.Before(f.Since) {
+			return false
+		}
+		if !f.Until.IsZero() && start.After(f.Until) {
+			return false
+		}
+	}
+	return true
+}
+
+// ParseAuditTime parses a time filter: an RFC 3339 timestamp, a date
+// (2006-01-02, local time) or a duration before now (e.g. 12h, 7d, 2w)
+func ParseAuditTime(value string, now time.Time) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+		return t, nil
+	}
+	if d, err := ParseRetentionDuration(value); err == nil {
+		return now.Add(-d), nil
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q (use RFC 3339, YYYY-MM-DD or a duration such as 24h or 7d)", value)
+}
+
+// CurrentUser returns the name of the OS user running cloudtm
+func CurrentUser() string {
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		return u.Username
+	}
+	for _, env := range []string{"USER", "USERNAME"} {
+		if name := os.Getenv(env); name != "" {
+			return name
+		}
+	}
+	return "unknown"
+}
+
+// GitCommit returns the commit checked out in dir, or "" outside a git repository
+func GitCommit(dir string) string {
+	cmd := exec.Command("git", "rev-parse", "HEAD")
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
@@ -0,0 +1,122 @@
+package helper
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// Names of the Terraform-compatible binaries cloudtm can run
+const (
+	BinaryTerraform = "terraform"
+	BinaryTofu      = "tofu"
+)
+
+// terraformBinary is the binary run by RunTerraformJSON, OutputTerraform and
+// TerraformCommand
+var terraformBinary = BinaryTerraform
+
+// UseBinary makes cloudtm run the Terraform-compatible binary at path
+func UseBinary(path string) {
+	terraformBinary = path
+}
+
+// TerraformCommand returns a command running the configured binary in dir
+func TerraformCommand(dir string, args ...string) *exec.Cmd {
+	tfCmd := exec.Command(terraformBinary, args...)
+	tfCmd.Dir = dir
+	return tfCmd
+}
+
+// Binary identifies the Terraform-compatible binary that produced a version
+type Binary struct {
+	Name    string `json:"name"`    // "terraform" or "tofu"
+	Version string `json:"version"` // e.g. "1.9.0"
+	Path    string `json:"-"`
+}
+
+// FindBinary resolves a binary name or path in PATH. An empty name looks for
+// terraform and then tofu.
+func FindBinary(name string) (string, error) {
+	if name != "" {
+		path, err := exec.LookPath(name)
+		if err != nil {
+			return "", fmt.Errorf("binary %q not found", name)
+		}
+		return path, nil
+	}
+	for _, candidate := range []string{BinaryTerraform, BinaryTofu} {
+		if path, err := exec.LookPath(candidate); err == nil {
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("neither terraform nor tofu found in PATH")
+}
+
+// DetectBinary runs '<path> version' and identifies the product and version
+// from its first line ("Terraform v1.9.0" or "OpenTofu v1.8.2")
+func DetectBinary(path string) (*Binary, error) {
+	out, err := exec.Command(path, "version").Output()
+	if err != nil {
+		return nil, fmt.Errorf("%s version: %w", path, err)
+	}
+	line, _, _ := strings.Cut(string(bytes.TrimSpace(out)), "\n")
+	words := strings.Fields(line)
+	if len(words) < 2 || !strings.HasPrefix(words[1], "v") {
+		return nil, fmt.Errorf("%s version: unrecognized output %q", path, line)
+	}
+
+	binary := &Binary{Version: strings.TrimPrefix(words[1], "v"), Path: path}
+	switch words[0] {
+	case "Terraform":
+		binary.Name = BinaryTerraform
+	case "OpenTofu":
+		binary.Name = BinaryTofu
+	default:
+		return nil, fmt.Errorf("%s version: unrecognized product %q", path, words[0])
+	}
+	return binary, nil
+}
+
+// String returns the product name and version, e.g. "OpenTofu 1.8.2"
+func (b *Binary) String() string {
+	if b.Name == BinaryTofu {
+		return "OpenTofu " + b.Version
+	}
+	return "Terraform " + b.Version
+}
+
+// Differs reports whether other is a different product or version
+func (b *Binary) Differs(other *Binary) bool {
+	return b.Name != other.Name || b.Version != other.Version
+}
+
+// SupportsJSONUI reports whether plan, apply and destroy accept -json, which
+// cloudtm relies on (Terraform 0.15.3, every OpenTofu release)
+func (b *Binary) SupportsJSONUI() bool {
+	return b.Name == BinaryTofu || versionAtLeast(b.Version, 0, 15, 3)
+}
+
+// SupportsReplace reports whether plan and apply accept -replace
+// (Terraform 0.15.2, every OpenTofu release)
+func (b *Binary) SupportsReplace() bool {
+	return b.Name == BinaryTofu || versionAtLeast(b.Version, 0, 15, 2)
+}
+
+// versionAtLeast compares a "major.minor.patch[-suffix]" version
+func versionAtLeast(version string, want ...int) bool {
+	version, _, _ = strings.Cut(version, "-")
+	parts := strings.Split(version, ".")
+	for i, w := range want {
+		n := 0
+		if i < len(parts) {
+			n, _ = strconv.Atoi(parts[i])
+		}
+		if n != w {
+			return n > w
+		}
+	}
+	return true
+}
@@ -0,0 +1,338 @@
+package helper
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+)
+
+// ChainLink ties a version to its content and to the previous version,
+// forming a hash chain over the version history. Editing a version's files
+// or metadata, or removing a version, breaks the chain.
+type ChainLink struct {
+	ContentHash  string `json:"contentHash"`            // hash of the version's files
+	Artifacts    bool   `json:"artifacts,omitempty"`    // ContentHash also covers the plan, variables and error log
+	Previous     string `json:"previous,omitempty"`     // previous version in the chain
+	PreviousHash string `json:"previousHash,omitempty"` // Hash of the previous version's link
+	Hash         string `json:"hash"`                   // hash of the metadata, ContentHash and PreviousHash
+	KeyID        string `json:"keyId,omitempty"`        // signing key (see PublicKeyID)
+	Signature    string `json:"signature,omitempty"`    // base64 ed25519 signature of Hash
+}
+
+// linkContent is what a link's Hash covers: the metadata fields fixed when
+// the version is created (tags and pins may change later) plus the hashes of
+// the content and the previous link
+type linkContent struct {
+	Version      string           `json:"version"`
+	Timestamp    string           `json:"timestamp"`
+	Message      string           `json:"message,omitempty"`
+	Resources    ResourceCounts   `json:"resources"`
+	Changes      []ResourceChange `json:"changes,omitempty"`
+	State        *StateInfo       `json:"state,omitempty"`
+	RestoredFrom string           `json:"restoredFrom,omitempty"`
+	Status       string           `json:"status,omitempty"`
+	Errors       []Diagnostic     `json:"errors,omitempty"`
+	Inputs       *TerraformInputs `json:"inputs,omitempty"`
+	Binary       *Binary          `json:"binary,omitempty"`
+	ContentHash  string           `json:"contentHash"`
+	Artifacts    bool             `json:"artifacts,omitempty"`
+	Previous     string           `json:"previous,omitempty"`
+	PreviousHash string           `json:"previousHash,omitempty"`
+}
+
+// ContentHash hashes the paths and contents of a version's files and, with
+// artifacts, every other file stored under versions/<version>/ (plan files,
+// variables, error log) by key and plaintext. It does not depend on how the
+// version is stored, so compressing, encrypting, rekeying or copying a
+// version to another store keeps the hash. Links created before artifacts
+// were covered only hash the files.
+func ContentHash(store SnapshotStore, version string, artifacts bool) (string, error) {
+	reader, err := OpenVersion(store, version)
+	if err != nil {
+		return "", err
+	}
+	files := append([]SnapshotFile(nil), reader.Files...)
+	sort.Slice(files, func(i, j int) bool { return files[i].Path < files[j].Path })
+
+	h := sha256.New()
+	for _, f := range files {
+		data, err := reader.Read(f)
+		if err != nil {
+			return "", fmt.Errorf("%s: %w", f.Path, err)
+		}
+		fmt.Fprintf(h, "%s\x00%x\n", f.Path, sha256.Sum256(data))
+	}
+	if artifacts {
+		if err := hashArtifacts(h, store, version); err != nil {
+			return "", err
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// hashArtifacts adds the keys and plaintext of the files stored with a
+// version besides its snapshot content to h, in sorted order
+func hashArtifacts(h io.Writer, store SnapshotStore, version string) error {
+	keys, err := store.List("versions/" + version + "/")
+	if err != nil {
+		return err
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if versionFormat(version, key) != "" {
+			continue
+		}
+		r, err := store.Get(key)
+		if err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+		data, err := io.ReadAll(r)
+		r.Close()
+		if err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+		// Decrypting a store redacts sensitive values, so only the
+		// redacted variables are covered
+		switch path.Base(key) {
+		case VariablesFileName:
+			data, err = redactVariablesFile(data)
+		case PlanJSONFileName:
+			data, err = redactPlanJSON(data)
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+		fmt.Fprintf(h, "%s\x00%x\n", key, sha256.Sum256(data))
+	}
+	return nil
+}
+
+// LinkHash computes the hash of a version's link
+func LinkHash(meta *Metadata, link *ChainLink) string {
+	data, _ := json.Marshal(linkContent{
+		Version:      meta.Version,
+		Timestamp:    meta.Timestamp,
+		Message:      meta.Message,
+		Resources:    meta.Resources,
+		Changes:      meta.Changes,
+		State:        meta.State,
+		RestoredFrom: meta.RestoredFrom,
+		Status:       meta.Status,
+		Errors:       meta.Errors,
+		Inputs:       meta.Inputs,
+		Binary:       meta.Binary,
+		ContentHash:  link.ContentHash,
+		Artifacts:    link.Artifacts,
+		Previous:     link.Previous,
+		PreviousHash: link.PreviousHash,
+	})
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// LatestLink returns the newest version that has a chain link, or nil
+func LatestLink(metas []*Metadata) *Metadata {
+	var latest *Metadata
+	for _, meta := range metas {
+		if meta.Chain == nil {
+			continue
+		}
+		if latest == nil || VersionNumber(meta.Version) > VersionNumber(latest.Version) {
+			latest = meta
+		}
+	}
+	return latest
+}
+
+// SealVersion adds a chain link to the metadata of a newly stored version,
+// linking it to the newest chained version in metas and signing it when key
+// is not nil. The metadata must be saved afterwards.
+func SealVersion(store SnapshotStore, meta *Metadata, metas []*Metadata, key ed25519.PrivateKey) error {
+	contentHash, err := ContentHash(store, meta.Version, true)
+	if err != nil {
+		return err
+	}
+
+	link := &ChainLink{ContentHash: contentHash, Artifacts: true}
+	var others []*Metadata
+	for _, m := range metas {
+		if m.Version != meta.Version {
+			others = append(others, m)
+		}
+	}
+	if prev := LatestLink(others); prev != nil {
+		link.Previous = prev.Version
+		link.PreviousHash = prev.Chain.Hash
+	}
+	link.Hash = LinkHash(meta, link)
+
+	if key != nil {
+		public := key.Public().(ed25519.PublicKey)
+		link.KeyID = PublicKeyID(public)
+		link.Signature = base64.StdEncoding.EncodeToString(ed25519.Sign(key, []byte(link.Hash)))
+	}
+	meta.Chain = link
+	return nil
+}
+
+// ChainProblem is a finding of VerifyChain. Warnings (e.g. versions created
+// before hash chaining) do not indicate tampering.
+type ChainProblem struct {
+	Version string
+	Detail  string
+	Warning bool
+}
+
+func (p ChainProblem) Error() string {
+	return p.Version + ": " + p.Detail
+}
+
+// VerifyChain checks the chain links of metas: that each version's files and
+// metadata still match its link, that the previous version exists and its
+// link is the one recorded, that no two versions claim the same predecessor
+// and, when publicKey is given, that signatures are valid. Versions in
+// pruned were deleted on purpose; links to them are reported as warnings.
+func VerifyChain(store SnapshotStore, metas []*Metadata, pruned map[string]bool, publicKey ed25519.PublicKey) []ChainProblem {
+	sorted := append([]*Metadata(nil), metas...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return VersionNumber(sorted[i].Version) < VersionNumber(sorted[j].Version)
+	})
+	byVersion := make(map[string]*Metadata)
+	for _, meta := range sorted {
+		byVersion[meta.Version] = meta
+	}
+
+	var problems []ChainProblem
+	report := func(version string, warning bool, format string, args ...interface{}) {
+		problems = append(problems, ChainProblem{Version: version, Detail: fmt.Sprintf(format, args...), Warning: warning})
+	}
+
+	successors := make(map[string][]string)
+	chainStart := ""
+	for _, meta := range sorted {
+		link := meta.Chain
+		if link == nil {
+			if chainStart != "" {
+				report(meta.Version, false, "no chain link, although the chain starts at %s", chainStart)
+			} else {
+				report(meta.Version, true, "no chain link (created before hash chaining)")
+			}
+			continue
+		}
+		if chainStart == "" {
+			chainStart = meta.Version
+		}
+
+		// Files
+		contentHash, err := ContentHash(store, meta.Version, link.Artifacts)
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+			report(meta.Version, false, "snapshot files are missing")
+		case err != nil:
+			report(meta.Version, false, "cannot read snapshot files: %v", err)
+		case contentHash != link.ContentHash:
+			report(meta.Version, false, "version files were modified (content hash %s, recorded %s)", short(contentHash), short(link.ContentHash))
+		}
+
+		// Metadata
+		if LinkHash(meta, link) != link.Hash {
+			report(meta.Version, false, "metadata was modified (link hash does not match)")
+		}
+
+		// Link to the previous version
+		if link.Previous != "" {
+			successors[link.Previous] = append(successors[link.Previous], meta.Version)
+			prev, ok := byVersion[link.Previous]
+			switch {
+			case !ok && pruned[link.Previous]:
+				report(meta.Version, true, "previous version %s was pruned; link not checked", link.Previous)
+			case !ok:
+				report(meta.Version, false, "previous version %s is missing", link.Previous)
+			case prev.Chain == nil || prev.Chain.Hash != link.PreviousHash:
+				report(meta.Version, false, "broken link: %s no longer has the recorded hash %s", link.Previous, short(link.PreviousHash))
+			}
+		}
+
+		// Signature
+		switch {
+		case publicKey == nil:
+		case link.Signature == "":
+			report(meta.Version, true, "not signed")
+		case link.KeyID != PublicKeyID(publicKey):
+			report(meta.Version, false, "signed by a different key (%s)", link.KeyID)
+		default:
+			sig, err := base64.StdEncoding.DecodeString(link.Signature)
+			if err != nil || !ed25519.Verify(publicKey, []byte(link.Hash), sig) {
+				report(meta.Version, false, "invalid signature")
+			}
+		}
+	}
+
+	for _, meta := range sorted {
+		if next := successors[meta.Version]; len(next) > 1 {
+			report(meta.Version, false, "chain forks: %v all link to it", next)
+		}
+	}
+	return problems
+}
+
+// VerifyChainHead checks that the newest version of the chain is the one the
+// project says it is: current.json must name a chained version, and the last
+// audit entry must agree with current.json. Without these checks deleting the
+// newest version (metadata and files) would leave a valid, shorter chain.
+// Versions newer than the current one are expected after a pull and are
+// reported as a warning.
+func VerifyChainHead(metas []*Metadata, current string, entries []AuditEntry) []ChainProblem {
+	var problems []ChainProblem
+	report := func(version string, warning bool, format string, args ...interface{}) {
+		problems = append(problems, ChainProblem{Version: version, Detail: fmt.Sprintf(format, args...), Warning: warning})
+	}
+
+	head := LatestLink(metas)
+	if head == nil {
+		return nil
+	}
+	if current != "" {
+		var currentMeta *Metadata
+		for _, meta := range metas {
+			if meta.Version == current {
+				currentMeta = meta
+			}
+		}
+		switch {
+		case currentMeta == nil:
+			report(current, false, "current version (current.json) is missing; the chain ends at %s", head.Version)
+		case currentMeta.Chain == nil:
+			report(current, false, "current version (current.json) has no chain link; the chain ends at %s", head.Version)
+		case VersionNumber(head.Version) > VersionNumber(current):
+			report(head.Version, true, "newer than the current version %s (pulled, never applied here)", current)
+		}
+	}
+
+	for i := len(entries) - 1; i >= 0; i-- {
+		if entries[i].Version == "" {
+			continue
+		}
+		if entries[i].Version != current {
+			report(entries[i].Version, false, "audit log records it as current after '%s' at %s, but current.json says %q",
+				entries[i].Command, entries[i].End, current)
+		}
+		break
+	}
+	return problems
+}
+
+func short(hash string) string {
+	if len(hash) > 12 {
+		return hash[:12]
+	}
+	return hash
+}
@@ -0,0 +1,64 @@
+package helper
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestVerifyChainHead(t *testing.T) {
+	chained := func(version string) *Metadata {
+		return &Metadata{Version: version, Chain: &ChainLink{Hash: "hash-" + version}}
+	}
+	metas := []*Metadata{{Version: "v1"}, chained("v2"), chained("v3")}
+	audit := func(versions ...string) []AuditEntry {
+		var entries []AuditEntry
+		for _, v := range versions {
+			entries = append(entries, AuditEntry{Command: "apply", Version: v})
+		}
+		return entries
+	}
+
+	tests := []struct {
+		name    string
+		metas   []*Metadata
+		current string
+		entries []AuditEntry
+		want    []string // versions reported as errors
+		warn    []string // versions reported as warnings
+	}{
+		{"head is current", metas, "v3", audit("v2", "v3"), nil, nil},
+		{"no audit entries", metas, "v3", nil, nil, nil},
+		{"last entry without a version", metas, "v3", audit("v3", ""), nil, nil},
+		{"newest version deleted", metas[:2], "v3", audit("v3"), []string{"v3"}, nil},
+		{"current.json rewound", metas[:2], "v2", audit("v3"), []string{"v3"}, nil},
+		{"current without a link", metas, "v1", audit("v1"), []string{"v1"}, nil},
+		{"pulled versions", metas, "v2", audit("v2"), nil, []string{"v3"}},
+		{"no chain", metas[:1], "v1", audit("v1"), nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var errs, warns []string
+			for _, p := range VerifyChainHead(tt.metas, tt.current, tt.entries) {
+				if p.Warning {
+					warns = append(warns, p.Version)
+				} else {
+					errs = append(errs, p.Version)
+				}
+			}
+			if !reflect.DeepEqual(errs, tt.want) || !reflect.DeepEqual(warns, tt.warn) {
+				t.Errorf("errors %v and warnings %v, want %v and %v", errs, warns, tt.want, tt.warn)
+			}
+		})
+	}
+}
+
+func TestPrunedVersions(t *testing.T) {
+	entries := []AuditEntry{
+		{Command: "prune", Pruned: []string{"v1", "v2"}, Hash: "a"},
+		{Command: "prune", Pruned: []string{"v3"}}, // not chained
+	}
+	want := map[string]bool{"v1": true, "v2": true}
+	if got := PrunedVersions(entries); !reflect.DeepEqual(got, want) {
+		t.Errorf("PrunedVersions = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,55 @@
+package helper
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+)
+
+// Config holds project settings stored in .cloudtm/config.json
+type Config struct {
+	// Remote is the snapshot store used by push and pull (path, file:// or s3:// URL)
+	Remote string `json:"remote,omitempty"`
+
+	// Compression stores new versions as compressed tar archives (none, gzip or zstd)
+	Compression string `json:"compression,omitempty"`
+
+	// EncryptionKeyFile enables AES-256-GCM encryption of snapshot content
+	// with the key stored in this file (see 'cloudtm keygen')
+	EncryptionKeyFile string `json:"encryptionKeyFile,omitempty"`
+
+	// SigningKeyFile enables ed25519 signatures of the hash chain links of
+	// new versions with the private key stored in this file
+	SigningKeyFile string `json:"signingKeyFile,omitempty"`
+
+	// TFBinary is the Terraform-compatible binary to run (a name in PATH such
+	// as "tofu", or a path). By default terraform is used, or tofu when
+	// terraform is not installed.
+	TFBinary string `json:"tfBinary,omitempty"`
+}
+
+// LoadConfig reads config.json, returning an empty config when it does not exist
+func LoadConfig(cloudtmDir string) (*Config, error) {
+	data, err := os.ReadFile(filepath.Join(cloudtmDir, "config.json"))
+	if os.IsNotExist(err) {
+		return &Config{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// SaveConfig writes config.json
+func SaveConfig(cloudtmDir string, cfg *Config) error {
+	data, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		return err
+	}
+	return WriteFileAtomic(filepath.Join(cloudtmDir, "config.json"), data, 0644)
+}
@@ -0,0 +1,287 @@
+package helper
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+)
+
+// encryptedMagic prefixes every encrypted object:
+// magic (8 bytes) | key id (8 bytes) | nonce (12 bytes) | AES-256-GCM ciphertext
+var encryptedMagic = []byte("CTMENC01")
+
+const keyIDSize = 8
+
+// ErrNoEncryptionKey is returned when reading an encrypted object without a key
+var ErrNoEncryptionKey = errors.New("snapshot is encrypted but no encryption key is configured (set CLOUDTM_ENCRYPTION_KEY, CLOUDTM_ENCRYPTION_KEY_FILE or encryptionKeyFile in config.json)")
+
+// GenerateKey returns a new random 256-bit key
+func GenerateKey() ([]byte, error) {
+	key := make([]byte, 32)
+	_, err := rand.Read(key)
+	return key, err
+}
+
+// EncodeKey encodes a key for storage in a key file or environment variable
+func EncodeKey(key []byte) string {
+	return base64.StdEncoding.EncodeToString(key)
+}
+
+// ParseKey decodes a base64 or hex encoded 256-bit key
+func ParseKey(encoded string) ([]byte, error) {
+	encoded = strings.TrimSpace(encoded)
+	if key, err := base64.StdEncoding.DecodeString(encoded); err == nil && len(key) == 32 {
+		return key, nil
+	}
+	if key, err := hex.DecodeString(encoded); err == nil && len(key) == 32 {
+		return key, nil
+	}
+	return nil, fmt.Errorf("encryption key must be 32 bytes, base64 or hex encoded")
+}
+
+// ReadKeyFile reads a key written by EncodeKey
+func ReadKeyFile(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	key, err := ParseKey(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return key, nil
+}
+
+// LoadEncryptionKey returns the configured snapshot encryption key, or nil when
+// encryption is not enabled. CLOUDTM_ENCRYPTION_KEY takes precedence over
+// CLOUDTM_ENCRYPTION_KEY_FILE, which takes precedence over config.json.
+func LoadEncryptionKey(cfg *Config) ([]byte, error) {
+	if encoded := os.Getenv("CLOUDTM_ENCRYPTION_KEY"); encoded != "" {
+		return ParseKey(encoded)
+	}
+	if path := os.Getenv("CLOUDTM_ENCRYPTION_KEY_FILE"); path != "" {
+		return ReadKeyFile(path)
+	}
+	if cfg != nil && cfg.EncryptionKeyFile != "" {
+		return ReadKeyFile(cfg.EncryptionKeyFile)
+	}
+	return nil, nil
+}
+
+// KeyID returns a short identifier of a key, stored with each encrypted object
+func KeyID(key []byte) []byte {
+	sum := sha256.Sum256(append([]byte("cloudtm-key-id:"), key...))
+	return sum[:keyIDSize]
+}
+
+// IsEncrypted reports whether data was produced by EncryptBytes
+func IsEncrypted(data []byte) bool {
+	return bytes.HasPrefix(data, encryptedMagic)
+}
+
+// EncryptBytes encrypts data with AES-256-GCM
+func EncryptBytes(key, plaintext []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	header := append(append(append([]byte{}, encryptedMagic...), KeyID(key)...), nonce...)
+	// The header is authenticated as additional data
+	return gcm.Seal(header, nonce, plaintext, header), nil
+}
+
+// DecryptBytes decrypts data produced by EncryptBytes
+func DecryptBytes(key, data []byte) ([]byte, error) {
+	if !IsEncrypted(data) {
+		return nil, fmt.Errorf("data is not encrypted")
+	}
+	if key == nil {
+		return nil, ErrNoEncryptionKey
+	}
+
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	headerSize := len(encryptedMagic) + keyIDSize + gcm.NonceSize()
+	if len(data) < headerSize {
+		return nil, fmt.Errorf("encrypted data is truncated")
+	}
+	if !bytes.Equal(data[len(encryptedMagic):len(encryptedMagic)+keyIDSize], KeyID(key)) {
+		return nil, fmt.Errorf("snapshot was encrypted with a different key")
+	}
+
+	header := data[:headerSize]
+	nonce := header[len(encryptedMagic)+keyIDSize:]
+	plaintext, err := gcm.Open(nil, nonce, data[headerSize:], header)
+	if err != nil {
+		return nil, fmt.Errorf("decrypting snapshot: %w", err)
+	}
+	return plaintext, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// EncryptedStore encrypts snapshot content (everything below objects/ and
+// versions/) on the way into the wrapped store and decrypts it on the way out.
+// Metadata is stored in the clear. Reading plaintext objects written before
+// encryption was enabled keeps working.
+type EncryptedStore struct {
+	Inner SnapshotStore
+	Key   []byte // nil disables encryption of new objects
+}
+
+// WithEncryption wraps a store so encrypted objects are decrypted with key and,
+// when key is set, new snapshot content is encrypted
+func WithEncryption(store SnapshotStore, key []byte) SnapshotStore {
+	return &EncryptedStore{Inner: store, Key: key}
+}
+
+// Encrypts reports whether new snapshot content written to store is encrypted
+func Encrypts(store SnapshotStore) bool {
+	s, ok := store.(*EncryptedStore)
+	return ok && s.Key != nil
+}
+
+// IsSecretKey reports whether objects at the store key hold snapshot content
+func IsSecretKey(key string) bool {
+	return strings.HasPrefix(key, "objects/") || strings.HasPrefix(key, "versions/") ||
+		strings.HasPrefix(key, stagingPrefix)
+}
+
+func (s *EncryptedStore) String() string {
+	return s.Inner.String()
+}
+
+// Put encrypts snapshot content before storing it
+func (s *EncryptedStore) Put(key string, r io.Reader) error {
+	if s.Key == nil || !IsSecretKey(key) {
+		return s.Inner.Put(key, r)
+	}
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	encrypted, err := EncryptBytes(s.Key, data)
+	if err != nil {
+		return err
+	}
+	return s.Inner.Put(key, bytes.NewReader(encrypted))
+}
+
+// Get decrypts encrypted objects and returns plaintext objects unchanged
+func (s *EncryptedStore) Get(key string) (io.ReadCloser, error) {
+	r, err := s.Inner.Get(key)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if IsEncrypted(data) {
+		if data, err = DecryptBytes(s.Key, data); err != nil {
+			return nil, fmt.Errorf("%s: %w", key, err)
+		}
+	}
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+// List lists the wrapped store
+func (s *EncryptedStore) List(prefix string) ([]string, error) {
+	return s.Inner.List(prefix)
+}
+
+// Delete deletes from the wrapped store
+func (s *EncryptedStore) Delete(key string) error {
+	return s.Inner.Delete(key)
+}
+
+// Rekey re-encrypts every snapshot object of the store with newKey. Objects
+// encrypted with oldKey are decrypted first; plaintext objects are encrypted.
+// A nil newKey decrypts the store. It returns the number of objects rewritten.
+func Rekey(store SnapshotStore, oldKey, newKey []byte) (int, error) {
+	var keys []string
+	for _, prefix := range []string{"objects/", "versions/"} {
+		found, err := store.List(prefix)
+		if err != nil {
+			return 0, err
+		}
+		keys = append(keys, found...)
+	}
+
+	rewritten := 0
+	for _, key := range keys {
+		r, err := store.Get(key)
+		if err != nil {
+			return rewritten, err
+		}
+		data, err := io.ReadAll(r)
+		r.Close()
+		if err != nil {
+			return rewritten, err
+		}
+
+		plaintext := data
+		if IsEncrypted(data) {
+			if newKey != nil && len(data) > len(encryptedMagic)+keyIDSize &&
+				bytes.Equal(data[len(encryptedMagic):len(encryptedMagic)+keyIDSize], KeyID(newKey)) {
+				continue
+			}
+			if plaintext, err = DecryptBytes(oldKey, data); err != nil {
+				return rewritten, fmt.Errorf("%s: %w", key, err)
+			}
+		} else if newKey == nil {
+			continue
+		}
+
+		output := plaintext
+		if newKey != nil {
+			if output, err = EncryptBytes(newKey, plaintext); err != nil {
+				return rewritten, err
+			}
+		} else {
+			// Sensitive variable values are only stored encrypted
+			switch path.Base(key) {
+			case VariablesFileName:
+				output, err = redactVariablesFile(plaintext)
+			case PlanJSONFileName:
+				output, err = redactPlanJSON(plaintext)
+			}
+			if err != nil {
+				return rewritten, fmt.Errorf("%s: %w", key, err)
+			}
+		}
+		if err := store.Put(key, bytes.NewReader(output)); err != nil {
+			return rewritten, fmt.Errorf("%s: %w", key, err)
+		}
+		rewritten++
+	}
+	return rewritten, nil
+}
@@ -0,0 +1,267 @@
+package helper
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func testKey(t *testing.T) []byte {
+	t.Helper()
+	key, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+// readKey returns the content of key in store
+func readKey(t *testing.T, store SnapshotStore, key string) []byte {
+	t.Helper()
+	r, err := store.Get(key)
+	if err != nil {
+		t.Fatalf("Get(%q): %v", key, err)
+	}
+	defer r.Close()
+	data, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestEncryptDecryptBytes(t *testing.T) {
+	key := testKey(t)
+	tests := []struct {
+		name      string
+		plaintext []byte
+	}{
+		{"empty", []byte{}},
+		{"text", []byte(`resource "null_resource" "a" {}`)},
+		{"binary", bytes.Repeat([]byte{0, 1, 2, 0xff}, 1000)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			encrypted, err := EncryptBytes(key, tt.plaintext)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !IsEncrypted(encrypted) {
+				t.Error("IsEncrypted = false for EncryptBytes output")
+			}
+			if len(tt.plaintext) > 0 && bytes.Contains(encrypted, tt.plaintext) {
+				t.Error("ciphertext contains the plaintext")
+			}
+			decrypted, err := DecryptBytes(key, encrypted)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(decrypted, tt.plaintext) {
+				t.Errorf("round trip = %q, want %q", decrypted, tt.plaintext)
+			}
+		})
+	}
+}
+
+func TestDecryptBytesErrors(t *testing.T) {
+	key := testKey(t)
+	encrypted, err := EncryptBytes(key, []byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tampered := append([]byte(nil), encrypted...)
+	tampered[len(tampered)-1] ^= 0xff
+
+	tests := []struct {
+		name    string
+		key     []byte
+		data    []byte
+		wantErr string
+	}{
+		{"wrong key", testKey(t), encrypted, "different key"},
+		{"no key", nil, encrypted, ErrNoEncryptionKey.Error()},
+		{"plaintext", key, []byte("secret"), "not encrypted"},
+		{"truncated", key, encrypted[:len(encryptedMagic)+keyIDSize+4], "truncated"},
+		{"tampered", key, tampered, "decrypting snapshot"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := DecryptBytes(tt.key, tt.data)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("got error %v, want one containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseKey(t *testing.T) {
+	key := testKey(t)
+	tests := []struct {
+		name    string
+		encoded string
+		wantErr bool
+	}{
+		{"base64", EncodeKey(key), false},
+		{"base64 with newline", EncodeKey(key) + "\n", false},
+		{"hex", hex.EncodeToString(key), false},
+		{"too short", EncodeKey(key[:16]), true},
+		{"garbage", "not a key", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parsed, err := ParseKey(tt.encoded)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseKey error = %v, want error %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !bytes.Equal(parsed, key) {
+				t.Error("ParseKey did not return the encoded key")
+			}
+		})
+	}
+}
+
+func TestEncryptedStore(t *testing.T) {
+	key := testKey(t)
+	inner := &FileStore{Root: t.TempDir()}
+	store := WithEncryption(inner, key)
+
+	tests := []struct {
+		key     string
+		secret  bool
+		content string
+	}{
+		{"objects/ab/abcdef", true, "blob content"},
+		{"versions/v1/manifest.json", true, `{"files":[]}`},
+		{"staging/v2/plan.json", true, `{"planned":true}`},
+		{"meta/v1.json", false, `{"version":"v1"}`},
+	}
+	for _, tt := range tests {
+		if err := store.Put(tt.key, strings.NewReader(tt.content)); err != nil {
+			t.Fatal(err)
+		}
+		if got := string(readKey(t, store, tt.key)); got != tt.content {
+			t.Errorf("%s: read %q through the store, want %q", tt.key, got, tt.content)
+		}
+		if raw := readKey(t, inner, tt.key); IsEncrypted(raw) != tt.secret {
+			t.Errorf("%s: stored encrypted = %v, want %v", tt.key, IsEncrypted(raw), tt.secret)
+		}
+	}
+
+	// Plaintext written before encryption was enabled stays readable
+	if err := inner.Put("objects/cd/cdef01", strings.NewReader("old plaintext")); err != nil {
+		t.Fatal(err)
+	}
+	if got := string(readKey(t, store, "objects/cd/cdef01")); got != "old plaintext" {
+		t.Errorf("plaintext object read as %q", got)
+	}
+
+	// Reading with the wrong key fails instead of returning ciphertext
+	if _, err := WithEncryption(inner, testKey(t)).Get("objects/ab/abcdef"); err == nil {
+		t.Error("Get with the wrong key succeeded")
+	}
+	if _, err := WithEncryption(inner, nil).Get("objects/ab/abcdef"); !errors.Is(err, ErrNoEncryptionKey) {
+		t.Errorf("Get without a key: got %v, want ErrNoEncryptionKey", err)
+	}
+}
+
+func TestRekey(t *testing.T) {
+	oldKey, newKey := testKey(t), testKey(t)
+	inner := &FileStore{Root: t.TempDir()}
+	contents := map[string]string{
+		"objects/ab/abcdef":         "blob",
+		"versions/v1/manifest.json": `{"files":[]}`,
+	}
+	for key, content := range contents {
+		if err := WithEncryption(inner, oldKey).Put(key, strings.NewReader(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Written before encryption was enabled
+	if err := inner.Put("objects/cd/cdef01", strings.NewReader("plain")); err != nil {
+		t.Fatal(err)
+	}
+	contents["objects/cd/cdef01"] = "plain"
+
+	checkStore := func(t *testing.T, key []byte, wantEncrypted bool) {
+		t.Helper()
+		for k, content := range contents {
+			raw := readKey(t, inner, k)
+			if IsEncrypted(raw) != wantEncrypted {
+				t.Errorf("%s: encrypted = %v, want %v", k, IsEncrypted(raw), wantEncrypted)
+			}
+			if got := string(readKey(t, WithEncryption(inner, key), k)); got != content {
+				t.Errorf("%s: read %q, want %q", k, got, content)
+			}
+		}
+	}
+
+	// Rekeying with the wrong old key fails without losing content
+	if _, err := Rekey(inner, testKey(t), newKey); err == nil || !strings.Contains(err.Error(), "different key") {
+		t.Fatalf("Rekey with the wrong old key: got %v", err)
+	}
+	if _, err := DecryptBytes(oldKey, readKey(t, inner, "versions/v1/manifest.json")); err != nil {
+		t.Fatalf("content no longer readable with the old key after a failed rekey: %v", err)
+	}
+
+	n, err := Rekey(inner, oldKey, newKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 3 {
+		t.Errorf("Rekey rewrote %d objects, want 3", n)
+	}
+	checkStore(t, newKey, true)
+	if _, err := WithEncryption(inner, oldKey).Get("objects/ab/abcdef"); err == nil {
+		t.Error("content still readable with the old key")
+	}
+
+	// Running it again skips objects already encrypted with the new key
+	if n, err := Rekey(inner, oldKey, newKey); err != nil || n != 0 {
+		t.Errorf("second Rekey = %d, %v; want 0 objects rewritten", n, err)
+	}
+
+	// Decrypting writes plaintext back
+	if _, err := Rekey(inner, newKey, nil); err != nil {
+		t.Fatal(err)
+	}
+	checkStore(t, nil, false)
+}
+
+func TestRekeyDecryptRedactsVariables(t *testing.T) {
+	key := testKey(t)
+	inner := &FileStore{Root: t.TempDir()}
+	vars := &VersionVariables{Variables: map[string]CapturedVariable{
+		"region":   {Value: []byte(`"eu-west-1"`)},
+		"password": {Value: []byte(`"hunter2"`), Sensitive: true},
+	}}
+	if err := SaveVariables(WithEncryption(inner, key), "v1", vars); err != nil {
+		t.Fatal(err)
+	}
+	if err := WithEncryption(inner, key).Put("versions/v1/"+PlanJSONFileName, strings.NewReader(testPlanJSON)); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Rekey(inner, key, nil); err != nil {
+		t.Fatal(err)
+	}
+	raw := readKey(t, inner, "versions/v1/"+VariablesFileName)
+	if bytes.Contains(raw, []byte("hunter2")) {
+		t.Error("sensitive value stored in plaintext after decrypting")
+	}
+	if plan := readKey(t, inner, "versions/v1/"+PlanJSONFileName); bytes.Contains(plan, []byte("hunter2")) {
+		t.Error("sensitive value stored in plan.json in plaintext after decrypting")
+	}
+	got, err := LoadVariables(inner, "v1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.Variables["password"].Redacted {
+		t.Error("sensitive variable not marked redacted")
+	}
+	if string(got.Variables["region"].Value) != `"eu-west-1"` {
+		t.Errorf("non-sensitive value = %s, want it kept", got.Variables["region"].Value)
+	}
+}
@@ -0,0 +1,282 @@
+package helper
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// diffContext is the number of unchanged lines shown around each change
+const diffContext = 3
+
+// LoadVersionFiles reads every file of a stored version into memory, keyed by
+// slash-separated path
+func LoadVersionFiles(store SnapshotStore, version string) (map[string][]byte, error) {
+	reader, err := OpenVersion(store, version)
+	if err != nil {
+		return nil, err
+	}
+
+	files := make(map[string][]byte, len(reader.Files))
+	for _, f := range reader.Files {
+		data, err := reader.Read(f)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", f.Path, err)
+		}
+		files[f.Path] = data
+	}
+	return files, nil
+}
+
+// LoadProjectFiles reads the files of a project directory that a snapshot
+// would capture, keyed by slash-separated path
+func LoadProjectFiles(projectDir string) (map[string][]byte, error) {
+	files := make(map[string][]byte)
+	err := WalkProjectFiles(projectDir, SnapshotExcludeDirs, SnapshotExcludeFiles, SnapshotExcludePatterns, func(src, relPath string, info os.FileInfo) error {
+		data, err := os.ReadFile(src)
+		if err != nil {
+			return err
+		}
+		files[filepath.ToSlash(relPath)] = data
+		return nil
+	})
+	return files, err
+}
+
+// IsStateFile reports whether a snapshot path holds Terraform state rather
+// than configuration
+func IsStateFile(path string) bool {
+	name := filepath.Base(path)
+	return strings.HasSuffix(name, ".tfstate") || strings.HasSuffix(name, ".tfstate.backup")
+}
+
+// DiffFileSets returns a unified diff of every file that differs between two
+// file sets, in path order. Files for which skip returns true are ignored.
+func DiffFileSets(aLabel, bLabel string, a, b map[string][]byte, skip func(path string) bool) string {
+	paths := make(map[string]bool)
+	for p := range a {
+		paths[p] = true
+	}
+	for p := range b {
+		paths[p] = true
+	}
+	sorted := make([]string, 0, len(paths))
+	for p := range paths {
+		if skip == nil || !skip(p) {
+			sorted = append(sorted, p)
+		}
+	}
+	sort.Strings(sorted)
+
+	var out strings.Builder
+	for _, p := range sorted {
+		aName, bName := aLabel+"/"+p, bLabel+"/"+p
+		aData, inA := a[p]
+		bData, inB := b[p]
+		if !inA {
+			aName = "/dev/null"
+		}
+		if !inB {
+			bName = "/dev/null"
+		}
+		out.WriteString(UnifiedDiff(aName, bName, aData, bData))
+	}
+	return out.String()
+}
+
+// UnifiedDiff returns the differences between a and b in unified diff format,
+// or "" when they are identical
+func UnifiedDiff(aName, bName string, a, b []byte) string {
+	if bytes.Equal(a, b) {
+		return ""
+	}
+	if bytes.IndexByte(a, 0) >= 0 || bytes.IndexByte(b, 0) >= 0 {
+		return fmt.Sprintf("Binary files %s and %s differ\n", aName, bName)
+	}
+
+	ops := diffLines(splitLines(a), splitLines(b))
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "--- %s\n+++ %s\n", aName, bName)
+	for _, h := range diffHunks(ops) {
+		fmt.Fprintf(&out, "@@ -%s +%s @@\n", hunkRange(h.aStart, h.aLines), hunkRange(h.bStart, h.bLines))
+		for _, op := range ops[h.from:h.to] {
+			out.WriteByte(op.kind)
+			out.WriteString(op.line)
+			if !strings.HasSuffix(op.line, "\n") {
+				out.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+	}
+	return out.String()
+}
+
+// splitLines splits data into lines, keeping line endings so a missing final
+// newline is detected as a change
+func splitLines(data []byte) []string {
+	if len(data) == 0 {
+		return nil
+	}
+	lines := strings.SplitAfter(string(data), "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// diffOp is one line of an edit script: ' ' unchanged, '-' removed, '+' added
+type diffOp struct {
+	kind byte
+	line string
+}
+
+// diffLines computes a shortest edit script from a to b with Myers' algorithm
+func diffLines(a, b []string) []diffOp {
+	n, m := len(a), len(b)
+	max := n + m
+	offset := max + 1
+	v := make([]int, 2*max+3)
+
+	// trace[d] holds the furthest reaching x per diagonal before step d
+	var trace [][]int
+search:
+	for d := 0; d <= max; d++ {
+		trace = append(trace, append([]int(nil), v...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				break search
+			}
+		}
+	}
+
+	// Walk the trace backwards to recover the edit script
+	var ops []diffOp
+	x, y := n, m
+	for d := len(trace) - 1; d >= 0; d-- {
+		v := trace[d]
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := v[offset+prevK]
+		prevY := prevX - prevK
+
+		for x > prevX && y > prevY {
+			ops = append(ops, diffOp{' ', a[x-1]})
+			x--
+			y--
+		}
+		if d > 0 {
+			if x == prevX {
+				ops = append(ops, diffOp{'+', b[y-1]})
+				y--
+			} else {
+				ops = append(ops, diffOp{'-', a[x-1]})
+				x--
+			}
+		}
+	}
+
+	for i, j := 0, len(ops)-1; i < j; i, j = i+1, j-1 {
+		ops[i], ops[j] = ops[j], ops[i]
+	}
+	return ops
+}
+
+// diffHunk is a range of ops printed together with its line ranges
+type diffHunk struct {
+	from, to       int // ops[from:to]
+	aStart, aLines int
+	bStart, bLines int
+}
+
+// diffHunks groups changed ops with diffContext unchanged lines around them,
+// merging groups whose context would overlap
+func diffHunks(ops []diffOp) []diffHunk {
+	var hunks []diffHunk
+	for i := 0; i < len(ops); {
+		if ops[i].kind == ' ' {
+			i++
+			continue
+		}
+
+		from := i - diffContext
+		if from < 0 {
+			from = 0
+		}
+		// Extend until more than 2*diffContext unchanged lines follow a change
+		to, unchanged := i, 0
+		for ; to < len(ops); to++ {
+			if ops[to].kind == ' ' {
+				unchanged++
+				if unchanged > 2*diffContext {
+					break
+				}
+			} else {
+				unchanged = 0
+			}
+		}
+		to -= unchanged
+		to += diffContext
+		if to > len(ops) {
+			to = len(ops)
+		}
+		hunks = append(hunks, diffHunk{from: from, to: to})
+		i = to
+	}
+
+	// Compute line numbers
+	aLine, bLine, op := 1, 1, 0
+	for h := range hunks {
+		for ; op < hunks[h].from; op++ {
+			aLine, bLine = advanceLines(ops[op], aLine, bLine)
+		}
+		hunks[h].aStart, hunks[h].bStart = aLine, bLine
+		for ; op < hunks[h].to; op++ {
+			aLine, bLine = advanceLines(ops[op], aLine, bLine)
+		}
+		hunks[h].aLines = aLine - hunks[h].aStart
+		hunks[h].bLines = bLine - hunks[h].bStart
+	}
+	return hunks
+}
+
+func advanceLines(op diffOp, aLine, bLine int) (int, int) {
+	switch op.kind {
+	case ' ':
+		return aLine + 1, bLine + 1
+	case '-':
+		return aLine + 1, bLine
+	default:
+		return aLine, bLine + 1
+	}
+}
+
+// hunkRange formats a hunk line range; empty ranges refer to the line before
+func hunkRange(start, lines int) string {
+	if lines == 0 {
+		return fmt.Sprintf("%d,0", start-1)
+	}
+	if lines == 1 {
+		return fmt.Sprintf("%d", start)
+	}
+	return fmt.Sprintf("%d,%d", start, lines)
+}
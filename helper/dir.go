@@ -6,8 +6,9 @@ import (
 	"strings"
 )
 
-// CopyDirectory copies a directory recursively, excluding specified paths and file patterns
-func CopyDirectory(src, dst string, excludeDirs []string, excludeFiles []string, excludePatterns []string) error {
+// WalkProjectFiles calls fn for every regular file below src, excluding specified
+// paths and file patterns. relPath is relative to src.
+func WalkProjectFiles(src string, excludeDirs []string, excludeFiles []string, excludePatterns []string, fn func(path, relPath string, info os.FileInfo) error) error {
 	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
@@ -30,29 +31,40 @@ func CopyDirectory(src, dst string, excludeDirs []string, excludeFiles []string,
 			}
 		}
 
+		if info.IsDir() {
+			return nil
+		}
+
 		// Skip excluded files (exact match)
-		if !info.IsDir() {
-			fileName := filepath.Base(path)
-			for _, excludeFile := range excludeFiles {
-				if fileName == excludeFile {
-					return nil
-				}
+		fileName := filepath.Base(path)
+		for _, excludeFile := range excludeFiles {
+			if fileName == excludeFile {
+				return nil
 			}
+		}
 
-			// Skip files matching patterns (e.g., *.log, *.tmp)
-			for _, pattern := range excludePatterns {
-				matched, err := filepath.Match(pattern, fileName)
-				if err == nil && matched {
-					return nil
-				}
+		// Skip files matching patterns (e.g., *.log, *.tmp)
+		for _, pattern := range excludePatterns {
+			matched, err := filepath.Match(pattern, fileName)
+			if err == nil && matched {
+				return nil
 			}
 		}
 
+		return fn(path, relPath, info)
+	})
+}
+
+// CopyDirectory copies a directory recursively, excluding specified paths and file patterns
+func CopyDirectory(src, dst string, excludeDirs []string, excludeFiles []string, excludePatterns []string) error {
+	if err := os.MkdirAll(dst, 0755); err != nil {
+		return err
+	}
+	return WalkProjectFiles(src, excludeDirs, excludeFiles, excludePatterns, func(path, relPath string, info os.FileInfo) error {
 		// Create destination path
 		dstPath := filepath.Join(dst, relPath)
-
-		if info.IsDir() {
-			return os.MkdirAll(dstPath, info.Mode())
+		if err := os.MkdirAll(filepath.Dir(dstPath), 0755); err != nil {
+			return err
 		}
 
 		// Copy file
@@ -0,0 +1,63 @@
+package helper
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// ErrorLogFileName is the Terraform error output stored with a failed version
+// in versions/<version>/
+const ErrorLogFileName = "error.log"
+
+// FailureLog renders the error output of a failed Terraform run: the exit
+// error, error diagnostics, the resources that failed and anything written to
+// stderr
+func FailureLog(result *RunResult, runErr error) []byte {
+	var buf bytes.Buffer
+	if runErr != nil {
+		fmt.Fprintf(&buf, "terraform: %v\n", runErr)
+	}
+	if result == nil {
+		return buf.Bytes()
+	}
+
+	for _, d := range result.Errors() {
+		fmt.Fprintf(&buf, "\nError: %s\n", d.Summary)
+		if d.Address != "" {
+			fmt.Fprintf(&buf, "  with %s\n", d.Address)
+		}
+		if d.Detail != "" {
+			fmt.Fprintf(&buf, "\n%s\n", strings.TrimRight(d.Detail, "\n"))
+		}
+	}
+	if len(result.Failed) > 0 {
+		buf.WriteString("\nFailed resources:\n")
+		for _, c := range result.Failed {
+			fmt.Fprintf(&buf, "  %s (%s)\n", c.Address, c.Action)
+		}
+	}
+	if result.Stderr != "" {
+		buf.WriteString("\nstderr:\n")
+		buf.WriteString(result.Stderr)
+	}
+	return buf.Bytes()
+}
+
+// SaveErrorLog stores the error output of a failed run with a version
+func SaveErrorLog(store SnapshotStore, version string, log []byte) error {
+	return store.Put("versions/"+version+"/"+ErrorLogFileName, bytes.NewReader(log))
+}
+
+// LoadErrorLog reads the error output stored with a failed version
+func LoadErrorLog(store SnapshotStore, version string) ([]byte, error) {
+	r, err := store.Get("versions/" + version + "/" + ErrorLogFileName)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	var buf bytes.Buffer
+	_, err = buf.ReadFrom(r)
+	return buf.Bytes(), err
+}
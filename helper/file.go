@@ -5,7 +5,7 @@ import (
 	"os"
 )
 
-// CopyFile copies a single file
+// CopyFile copies a single file, replacing dst atomically
 func CopyFile(src, dst string) error {
 	sourceFile, err := os.Open(src)
 	if err != nil {
@@ -13,20 +13,13 @@ func CopyFile(src, dst string) error {
 	}
 	defer sourceFile.Close()
 
-	destFile, err := os.Create(dst)
-	if err != nil {
-		return err
-	}
-	defer destFile.Close()
-
-	if _, err := io.Copy(destFile, sourceFile); err != nil {
-		return err
-	}
-
 	// Copy file permissions
-	sourceInfo, err := os.Stat(src)
+	sourceInfo, err := sourceFile.Stat()
 	if err != nil {
 		return err
 	}
-	return os.Chmod(dst, sourceInfo.Mode())
+	return writeAtomic(dst, sourceInfo.Mode(), func(w io.Writer) error {
+		_, err := io.Copy(w, sourceFile)
+		return err
+	})
 }
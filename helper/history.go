@@ -0,0 +1,137 @@
+package helper
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// HistoryFileName is the append-only event log kept in .cloudtm/
+const HistoryFileName = "history.jsonl"
+
+// Kinds of history events
+const (
+	EventApply           = "apply"             // cloudtm apply created a version
+	EventApplyFailed     = "apply-failed"      // a failed apply was captured as a version
+	EventSnapshot        = "snapshot"          // cloudtm snapshot created a version
+	EventDestroy         = "destroy"           // cloudtm destroy
+	EventRollback        = "rollback"          // a version was applied in .cloudtm/rollback
+	EventRollbackDestroy = "rollback-destroy"  // the active rollback was destroyed (rollback --del)
+	EventRollbackInPlace = "rollback-in-place" // rollback --in-place created a version
+	EventPromote         = "promote"           // rollback --promote created a version
+	EventPrune           = "prune"             // a version was deleted by cloudtm prune
+)
+
+// HistoryEvent is one line of history.jsonl
+type HistoryEvent struct {
+	Timestamp string          `json:"timestamp"`
+	Event     string          `json:"event"`
+	Version   string          `json:"version,omitempty"` // version created, deleted or current
+	Source    string          `json:"source,omitempty"`  // version a rollback restored
+	Resources *ResourceCounts `json:"resources,omitempty"`
+	Status    string          `json:"status,omitempty"` // StatusFailed when the run failed part way
+	Message   string          `json:"message,omitempty"`
+}
+
+// NewHistoryEvent returns an event of the given kind happening now
+func NewHistoryEvent(event string) *HistoryEvent {
+	return &HistoryEvent{
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+		Event:     event,
+	}
+}
+
+// SummaryCounts converts a Terraform change summary into resource counts
+func SummaryCounts(s *ChangeSummary) *ResourceCounts {
+	if s == nil {
+		return nil
+	}
+	return &ResourceCounts{Added: s.Add, Changed: s.Change, Destroyed: s.Remove}
+}
+
+// AppendHistory appends an event to history.jsonl
+func AppendHistory(cloudtmDir string, event *HistoryEvent) error {
+	line, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(filepath.Join(cloudtmDir, HistoryFileName), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// ReadHistory returns the events of history.jsonl in the order they were
+// recorded. A missing file is an empty history.
+func ReadHistory(cloudtmDir string) ([]HistoryEvent, error) {
+	f, err := os.Open(filepath.Join(cloudtmDir, HistoryFileName))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var events []HistoryEvent
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for n := 1; scanner.Scan(); n++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var event HistoryEvent
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			return events, fmt.Errorf("%s line %d: %w", HistoryFileName, n, err)
+		}
+		events = append(events, event)
+	}
+	return events, scanner.Err()
+}
+
+// Timeline merges the recorded history with the versions' metadata, oldest
+// first. Versions created before history was recorded appear as apply
+// events built from their metadata.
+func Timeline(history []HistoryEvent, metas []*Metadata) []HistoryEvent {
+	recorded := make(map[string]bool)
+	for _, e := range history {
+		switch e.Event {
+		case EventApply, EventApplyFailed, EventSnapshot, EventRollbackInPlace, EventPromote:
+			recorded[e.Version] = true
+		}
+	}
+
+	timeline := append([]HistoryEvent(nil), history...)
+	for _, meta := range metas {
+		if recorded[meta.Version] {
+			continue
+		}
+		counts := meta.Resources
+		event := EventApply
+		if meta.Failed() {
+			event = EventApplyFailed
+		}
+		timeline = append(timeline, HistoryEvent{
+			Timestamp: meta.Timestamp,
+			Event:     event,
+			Version:   meta.Version,
+			Source:    meta.RestoredFrom,
+			Resources: &counts,
+			Message:   meta.Message,
+		})
+	}
+
+	sort.SliceStable(timeline, func(i, j int) bool {
+		return timeline[i].Timestamp < timeline[j].Timestamp
+	})
+	return timeline
+}
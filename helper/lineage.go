@@ -0,0 +1,99 @@
+package helper
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+)
+
+// StateInfo identifies the Terraform state captured by a version. The
+// lineage is assigned when a state is first created and never changes; the
+// serial increases with every write.
+type StateInfo struct {
+	Lineage string `json:"lineage"`
+	Serial  uint64 `json:"serial"`
+}
+
+// Info returns the state's lineage and serial
+func (s *TerraformState) Info() *StateInfo {
+	return &StateInfo{Lineage: s.Lineage, Serial: s.Serial}
+}
+
+// ReadStateInfo returns the lineage and serial of the project's local state,
+// or nil when there is no local state file
+func ReadStateInfo(projectDir string) (*StateInfo, error) {
+	state, err := ReadState(filepath.Join(projectDir, StateFileName))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return state.Info(), nil
+}
+
+// VersionStateInfo returns the lineage and serial of the state captured by a
+// version: from its metadata when recorded, otherwise from the snapshot's
+// state file (versions created before lineage was recorded). It returns nil
+// when the version has no state.
+func VersionStateInfo(store SnapshotStore, meta *Metadata) (*StateInfo, error) {
+	if meta.State != nil {
+		return meta.State, nil
+	}
+
+	reader, err := OpenVersion(store, meta.Version)
+	if err != nil {
+		return nil, err
+	}
+	data, err := reader.ReadFile(StateFileName)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	state, err := ParseState(data)
+	if err != nil {
+		return nil, err
+	}
+	return state.Info(), nil
+}
+
+// LatestStateInfo returns the newest version that recorded state info, or nil
+func LatestStateInfo(metas []*Metadata) *Metadata {
+	var latest *Metadata
+	for _, meta := range metas {
+		if meta.State == nil || meta.State.Lineage == "" {
+			continue
+		}
+		if latest == nil || VersionNumber(meta.Version) > VersionNumber(latest.Version) {
+			latest = meta
+		}
+	}
+	return latest
+}
+
+// LineageError reports states that belong to different lineages, i.e. were
+// not produced by the same sequence of Terraform runs
+type LineageError struct {
+	Version  string
+	Expected string // lineage of the version
+	Actual   string // lineage of the live state
+}
+
+func (e *LineageError) Error() string {
+	return fmt.Sprintf("state lineage of %s (%s) differs from the live state (%s)", e.Version, e.Expected, e.Actual)
+}
+
+// CheckLineage returns a LineageError when both states have a lineage and
+// they differ
+func CheckLineage(version string, versionState, liveState *StateInfo) error {
+	if versionState == nil || liveState == nil || versionState.Lineage == "" || liveState.Lineage == "" {
+		return nil
+	}
+	if versionState.Lineage != liveState.Lineage {
+		return &LineageError{Version: version, Expected: versionState.Lineage, Actual: liveState.Lineage}
+	}
+	return nil
+}
@@ -0,0 +1,188 @@
+package helper
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// LockFileName is the advisory lock held in .cloudtm/ by mutating commands
+const LockFileName = "lock.json"
+
+// lockPollInterval is how often a waiting command retries the lock
+const lockPollInterval = 500 * time.Millisecond
+
+// LockInfo describes the holder of the lock
+type LockInfo struct {
+	ID      string `json:"id"`
+	PID     int    `json:"pid"`
+	User    string `json:"user"`
+	Host    string `json:"host"`
+	Command string `json:"command"`
+	Start   string `json:"start"`
+}
+
+func (l *LockInfo) String() string {
+	return fmt.Sprintf("'%s' (pid %d, %s@%s) since %s", l.Command, l.PID, l.User, l.Host, l.Start)
+}
+
+// LockedError is returned when the lock is held by another process
+type LockedError struct {
+	Holder *LockInfo
+}
+
+func (e *LockedError) Error() string {
+	return "the .cloudtm directory is locked by " + e.Holder.String()
+}
+
+// NewLockInfo describes the current process as the holder of the lock
+func NewLockInfo(command string) *LockInfo {
+	id := make([]byte, 8)
+	rand.Read(id)
+	host, _ := os.Hostname()
+	return &LockInfo{
+		ID:      hex.EncodeToString(id),
+		PID:     os.Getpid(),
+		User:    CurrentUser(),
+		Host:    host,
+		Command: command,
+		Start:   time.Now().UTC().Format(time.RFC3339),
+	}
+}
+
+// AcquireLock creates the lock file for info, waiting up to timeout while
+// another process holds it. A lock left by a process on this host that no
+// longer runs is removed. It returns a *LockedError when the lock could not
+// be acquired in time.
+func AcquireLock(cloudtmDir string, info *LockInfo, timeout time.Duration) error {
+	path := filepath.Join(cloudtmDir, LockFileName)
+	data, err := json.MarshalIndent(info, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	deadline := time.Now().Add(timeout)
+	for {
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if err == nil {
+			_, err = f.Write(data)
+			if closeErr := f.Close(); err == nil {
+				err = closeErr
+			}
+			if err != nil {
+				os.Remove(path)
+			}
+			return err
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return err
+		}
+
+		holder, err := ReadLock(cloudtmDir)
+		if err != nil {
+			return err
+		}
+		if holder != nil && holder.IsStale() {
+			// The holder crashed or was killed; take over its lock
+			if err := removeStaleLock(path, holder, info.ID); err != nil {
+				return err
+			}
+			continue
+		}
+		if holder != nil && !time.Now().Before(deadline) {
+			return &LockedError{Holder: holder}
+		}
+		time.Sleep(lockPollInterval)
+	}
+}
+
+// removeStaleLock removes the lock file at path if it still belongs to the
+// stale holder. Another waiter may have replaced the stale lock with its own
+// since it was read, so the file is first renamed to a name unique to id and
+// only removed when it is the stale lock; otherwise it is put back.
+func removeStaleLock(path string, holder *LockInfo, id string) error {
+	claimed := path + ".stale-" + id
+	if err := os.Rename(path, claimed); err != nil {
+		if os.IsNotExist(err) {
+			// Another waiter removed it first
+			return nil
+		}
+		return err
+	}
+
+	data, err := os.ReadFile(claimed)
+	if err != nil {
+		return err
+	}
+	var current LockInfo
+	if json.Unmarshal(data, &current) == nil && current.ID == holder.ID {
+		return os.Remove(claimed)
+	}
+
+	// A live lock was taken; put it back unless yet another lock exists
+	if err := os.Link(claimed, path); err != nil && !os.IsExist(err) {
+		return err
+	}
+	return os.Remove(claimed)
+}
+
+// IsStale reports whether the lock was taken by a process on this host that
+// no longer runs
+func (l *LockInfo) IsStale() bool {
+	host, _ := os.Hostname()
+	return l.Host == host && l.PID > 0 && !processRunning(l.PID)
+}
+
+// ReleaseLock removes the lock file if it is still held by info
+func ReleaseLock(cloudtmDir string, info *LockInfo) error {
+	holder, err := ReadLock(cloudtmDir)
+	if err != nil || holder == nil {
+		return err
+	}
+	if holder.ID != info.ID {
+		return fmt.Errorf("lock is now held by %s", holder)
+	}
+	return os.Remove(filepath.Join(cloudtmDir, LockFileName))
+}
+
+// ReadLock returns the current holder of the lock, or nil when it is not held
+func ReadLock(cloudtmDir string) (*LockInfo, error) {
+	data, err := os.ReadFile(filepath.Join(cloudtmDir, LockFileName))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 0 {
+		// Being written by its holder
+		return &LockInfo{Command: "unknown"}, nil
+	}
+	var info LockInfo
+	if err := json.Unmarshal(data, &info); err != nil {
+		return nil, fmt.Errorf("%s: %w", LockFileName, err)
+	}
+	return &info, nil
+}
+
+// ForceUnlock removes the lock regardless of its holder and returns the
+// holder it removed, or nil when the lock was not held
+func ForceUnlock(cloudtmDir string) (*LockInfo, error) {
+	holder, err := ReadLock(cloudtmDir)
+	if err != nil {
+		// An unreadable lock file is removed as well
+		holder = &LockInfo{Command: "unknown"}
+	}
+	if err := os.Remove(filepath.Join(cloudtmDir, LockFileName)); err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return holder, nil
+}
@@ -0,0 +1,30 @@
+//go:build !windows
+
+package helper
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// processRunning reports whether a process with the given PID exists
+func processRunning(pid int) bool {
+	err := syscall.Kill(pid, 0)
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
+
+// lockFile takes an exclusive lock on f, waiting while another process holds it
+func lockFile(f *os.File) error {
+	for {
+		err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX)
+		if err != syscall.EINTR {
+			return err
+		}
+	}
+}
+
+// unlockFile releases the lock taken by lockFile
+func unlockFile(f *os.File) error {
+	return syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+}
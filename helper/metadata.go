@@ -0,0 +1,303 @@
+package helper
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MetadataSchemaVersion is the schema version written by this release.
+// Files without a schemaVersion field are schema 1 (counts stored as strings).
+const MetadataSchemaVersion = 2
+
+// Metadata describes a single version in meta/<version>.json
+type Metadata struct {
+	SchemaVersion int              `json:"schemaVersion"`
+	Version       string           `json:"version"`
+	Timestamp     string           `json:"timestamp"`
+	Message       string           `json:"message,omitempty"`
+	Resources     ResourceCounts   `json:"resources"`
+	Changes       []ResourceChange `json:"changes,omitempty"`
+	State         *StateInfo       `json:"state,omitempty"`
+	Tags          []Tag            `json:"tags,omitempty"`
+	Pinned        bool             `json:"pinned,omitempty"`
+	RestoredFrom  string           `json:"restoredFrom,omitempty"`
+	Status        string           `json:"status,omitempty"`
+	Inputs        *TerraformInputs `json:"inputs,omitempty"`
+	Binary        *Binary          `json:"binary,omitempty"`
+	Errors        []Diagnostic     `json:"errors,omitempty"`
+	Chain         *ChainLink       `json:"chain,omitempty"`
+
+	// Extra keeps fields unknown to this release so rewriting a file
+	// produced by a newer cloudtm does not drop them
+	Extra map[string]json.RawMessage `json:"-"`
+}
+
+// StatusFailed marks a version captured after a failed or partial apply. Its
+// state is whatever Terraform managed to write before the error.
+const StatusFailed = "failed"
+
+// Failed reports whether the version was captured after a failed apply
+func (m *Metadata) Failed() bool {
+	return m.Status == StatusFailed
+}
+
+// ResourceCounts holds the number of resources changed by a version
+type ResourceCounts struct {
+	Added     int `json:"added"`
+	Changed   int `json:"changed"`
+	Destroyed int `json:"destroyed"`
+}
+
+// MetadataError reports a metadata file that could not be read
+type MetadataError struct {
+	File string
+	Err  error
+}
+
+func (e MetadataError) Error() string {
+	return fmt.Sprintf("%s: %v", e.File, e.Err)
+}
+
+// metadataFields is an alias without methods used to avoid recursive (un)marshaling
+type metadataFields Metadata
+
+// NewMetadata returns metadata for a version created now
+func NewMetadata(version string) *Metadata {
+	return &Metadata{
+		SchemaVersion: MetadataSchemaVersion,
+		Version:       version,
+		Timestamp:     time.Now().UTC().Format(time.RFC3339),
+	}
+}
+
+// Time parses the metadata timestamp
+func (m *Metadata) Time() (time.Time, error) {
+	return time.Parse(time.RFC3339, m.Timestamp)
+}
+
+// UnmarshalJSON decodes metadata of any schema version, keeping unknown fields in Extra
+func (m *Metadata) UnmarshalJSON(data []byte) error {
+	var fields metadataFields
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	for _, known := range []string{"schemaVersion", "version", "timestamp", "message", "resources", "changes", "state", "tags", "pinned", "restoredFrom", "status", "errors", "inputs", "binary", "chain"} {
+		delete(raw, known)
+	}
+	if len(raw) > 0 {
+		fields.Extra = raw
+	}
+
+	if fields.SchemaVersion == 0 {
+		fields.SchemaVersion = 1
+	}
+	*m = Metadata(fields)
+	return nil
+}
+
+// MarshalJSON encodes metadata, writing back any unknown fields from Extra
+func (m Metadata) MarshalJSON() ([]byte, error) {
+	data, err := json.Marshal(metadataFields(m))
+	if err != nil || len(m.Extra) == 0 {
+		return data, err
+	}
+
+	var merged map[string]json.RawMessage
+	if err := json.Unmarshal(data, &merged); err != nil {
+		return nil, err
+	}
+	for key, value := range m.Extra {
+		if _, known := merged[key]; !known {
+			merged[key] = value
+		}
+	}
+	return json.Marshal(merged)
+}
+
+// UnmarshalJSON accepts counts stored as numbers or, for schema 1, as strings
+func (c *ResourceCounts) UnmarshalJSON(data []byte) error {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	var err error
+	if c.Added, err = decodeCount(raw["added"]); err != nil {
+		return fmt.Errorf("resources.added: %w", err)
+	}
+	if c.Changed, err = decodeCount(raw["changed"]); err != nil {
+		return fmt.Errorf("resources.changed: %w", err)
+	}
+	if c.Destroyed, err = decodeCount(raw["destroyed"]); err != nil {
+		return fmt.Errorf("resources.destroyed: %w", err)
+	}
+	return nil
+}
+
+func decodeCount(raw json.RawMessage) (int, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return 0, nil
+	}
+
+	var n int
+	if err := json.Unmarshal(raw, &n); err == nil {
+		return n, nil
+	}
+
+	var s string
+	if err := json.Unmarshal(raw, &s); err != nil {
+		return 0, fmt.Errorf("invalid count %s", raw)
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil {
+		return 0, fmt.Errorf("invalid count %q", s)
+	}
+	return n, nil
+}
+
+// DecodeMetadata parses and validates a metadata document
+func DecodeMetadata(data []byte) (*Metadata, error) {
+	var meta Metadata
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return nil, err
+	}
+	if meta.Version == "" {
+		return nil, fmt.Errorf("missing version")
+	}
+	if _, err := meta.Time(); err != nil {
+		return nil, fmt.Errorf("invalid timestamp %q", meta.Timestamp)
+	}
+	return &meta, nil
+}
+
+// metadataPath returns the path of meta/<version>.json
+func metadataPath(cloudtmDir, version string) string {
+	return filepath.Join(cloudtmDir, "meta", version+".json")
+}
+
+// ReadMetadata reads meta/<version>.json
+func ReadMetadata(cloudtmDir, version string) (*Metadata, error) {
+	data, err := os.ReadFile(metadataPath(cloudtmDir, version))
+	if err != nil {
+		return nil, err
+	}
+	return DecodeMetadata(data)
+}
+
+// SaveMetadata writes meta/<version>.json and returns its path
+func SaveMetadata(cloudtmDir string, meta *Metadata) (string, error) {
+	metaDest := metadataPath(cloudtmDir, meta.Version)
+	return metaDest, writeMetadataFile(metaDest, meta)
+}
+
+func writeMetadataFile(path string, meta *Metadata) error {
+	metaJSON, err := json.MarshalIndent(meta, "", "  ")
+	if err != nil {
+		return err
+	}
+	return WriteFileAtomic(path, metaJSON, 0644)
+}
+
+// LoadAllMetadata reads every metadata file in meta/, sorted by version number.
+// Files that cannot be read or decoded are returned as errors instead of aborting.
+func LoadAllMetadata(cloudtmDir string) ([]*Metadata, []MetadataError, error) {
+	metaDir := filepath.Join(cloudtmDir, "meta")
+	files, err := os.ReadDir(metaDir)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var all []*Metadata
+	var errs []MetadataError
+	for _, file := range files {
+		if file.IsDir() || !strings.HasSuffix(file.Name(), ".json") {
+			continue
+		}
+
+		data, err := os.ReadFile(filepath.Join(metaDir, file.Name()))
+		if err != nil {
+			errs = append(errs, MetadataError{File: file.Name(), Err: err})
+			continue
+		}
+
+		meta, err := DecodeMetadata(data)
+		if err != nil {
+			errs = append(errs, MetadataError{File: file.Name(), Err: err})
+			continue
+		}
+		all = append(all, meta)
+	}
+
+	sort.Slice(all, func(i, j int) bool {
+		return VersionNumber(all[i].Version) < VersionNumber(all[j].Version)
+	})
+	return all, errs, nil
+}
+
+// MigrateMetadata upgrades every metadata file in meta/ to the current schema
+// in place. It returns the files that were upgraded and the ones that could not be.
+func MigrateMetadata(cloudtmDir string) ([]string, []MetadataError, error) {
+	metaDir := filepath.Join(cloudtmDir, "meta")
+	files, err := os.ReadDir(metaDir)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var migrated []string
+	var errs []MetadataError
+	for _, file := range files {
+		if file.IsDir() || !strings.HasSuffix(file.Name(), ".json") {
+			continue
+		}
+
+		path := filepath.Join(metaDir, file.Name())
+		data, err := os.ReadFile(path)
+		if err != nil {
+			errs = append(errs, MetadataError{File: file.Name(), Err: err})
+			continue
+		}
+
+		var meta Metadata
+		if err := json.Unmarshal(data, &meta); err != nil {
+			errs = append(errs, MetadataError{File: file.Name(), Err: err})
+			continue
+		}
+		if meta.SchemaVersion >= MetadataSchemaVersion {
+			continue
+		}
+
+		// Schema 1 → 2: numeric counts (handled by decoding) and an explicit schema version
+		if meta.Version == "" {
+			meta.Version = strings.TrimSuffix(file.Name(), ".json")
+		}
+		meta.SchemaVersion = MetadataSchemaVersion
+
+		if err := writeMetadataFile(path, &meta); err != nil {
+			errs = append(errs, MetadataError{File: file.Name(), Err: err})
+			continue
+		}
+		migrated = append(migrated, file.Name())
+	}
+
+	return migrated, errs, nil
+}
+
+// VersionNumber extracts the numeric part of a version name (e.g., "v10" -> 10)
+func VersionNumber(version string) int {
+	num, err := strconv.Atoi(strings.TrimPrefix(version, "v"))
+	if err != nil {
+		return 0
+	}
+	return num
+}
@@ -0,0 +1,105 @@
+package helper
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// objectKey returns the store key of the blob with the given SHA-256 hash
+func objectKey(hash string) string {
+	return "objects/" + hash[:2] + "/" + hash
+}
+
+// HashBytes returns the hex SHA-256 of data
+func HashBytes(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// ListObjects returns the set of blob hashes present in the store
+func ListObjects(store SnapshotStore) (map[string]bool, error) {
+	keys, err := store.List("objects/")
+	if err != nil {
+		return nil, err
+	}
+
+	objects := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		objects[key[strings.LastIndex(key, "/")+1:]] = true
+	}
+	return objects, nil
+}
+
+// PutObject stores data as a blob unless a blob with the same hash is already
+// known in existing. It returns the hash and whether a new blob was written.
+func PutObject(store SnapshotStore, data []byte, existing map[string]bool) (string, bool, error) {
+	hash := HashBytes(data)
+	if existing[hash] {
+		return hash, false, nil
+	}
+	if err := store.Put(objectKey(hash), bytes.NewReader(data)); err != nil {
+		return "", false, err
+	}
+	existing[hash] = true
+	return hash, true, nil
+}
+
+// GetObject reads a blob and verifies its content against the hash
+func GetObject(store SnapshotStore, hash string) ([]byte, error) {
+	if len(hash) != sha256.Size*2 {
+		return nil, fmt.Errorf("invalid object hash %q", hash)
+	}
+
+	r, err := store.Get(objectKey(hash))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if actual := HashBytes(data); actual != hash {
+		return nil, fmt.Errorf("object %s is corrupt (content hash %s)", hash, actual)
+	}
+	return data, nil
+}
+
+// ObjectProblem reports a missing or corrupt blob referenced by a version
+type ObjectProblem struct {
+	Version string
+	Path    string
+	Hash    string
+	Err     error
+}
+
+func (p ObjectProblem) Error() string {
+	if p.Hash == "" {
+		return fmt.Sprintf("%s: %s: %v", p.Version, p.Path, p.Err)
+	}
+	return fmt.Sprintf("%s: %s (%s): %v", p.Version, p.Path, p.Hash, p.Err)
+}
+
+// CheckVersion verifies a version's snapshot content. For manifests every
+// referenced blob must exist and match its hash; archives and legacy
+// directories must be fully readable. It returns the storage format and the
+// number of files checked.
+func CheckVersion(store SnapshotStore, version string) (string, int, []ObjectProblem, error) {
+	reader, err := OpenVersion(store, version)
+	if err != nil {
+		return "", 0, nil, err
+	}
+
+	var problems []ObjectProblem
+	for _, f := range reader.Files {
+		if _, err := reader.Read(f); err != nil {
+			problems = append(problems, ObjectProblem{Version: version, Path: f.Path, Hash: f.SHA256, Err: err})
+		}
+	}
+	return reader.Format, len(reader.Files), problems, nil
+}
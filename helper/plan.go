@@ -0,0 +1,187 @@
+package helper
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Names of the plan files stored alongside each version
+const (
+	PlanFileName     = "plan.tfplan"
+	PlanJSONFileName = "plan.json"
+)
+
+// Plan is the subset of 'terraform show -json <planfile>' used by cloudtm
+type Plan struct {
+	FormatVersion    string                  `json:"format_version"`
+	TerraformVersion string                  `json:"terraform_version"`
+	ResourceChanges  []PlanResourceChange    `json:"resource_changes"`
+	Variables        map[string]PlanVariable `json:"variables"`
+	Configuration    struct {
+		RootModule struct {
+			Variables map[string]struct {
+				Sensitive bool `json:"sensitive"`
+			} `json:"variables"`
+		} `json:"root_module"`
+	} `json:"configuration"`
+}
+
+// PlanResourceChange describes the planned change of a single resource instance
+type PlanResourceChange struct {
+	Address      string `json:"address"`
+	Mode         string `json:"mode"`
+	Type         string `json:"type"`
+	Name         string `json:"name"`
+	ProviderName string `json:"provider_name"`
+	Change       struct {
+		Actions []string `json:"actions"`
+	} `json:"change"`
+}
+
+// Action collapses Terraform's action list into a single action name
+// (create, update, delete, replace, read or no-op)
+func (c PlanResourceChange) Action() string {
+	actions := c.Change.Actions
+	if len(actions) == 2 {
+		return "replace"
+	}
+	if len(actions) == 1 {
+		return actions[0]
+	}
+	return strings.Join(actions, ",")
+}
+
+// ParsePlan decodes the output of 'terraform show -json <planfile>'
+func ParsePlan(data []byte) (*Plan, error) {
+	var plan Plan
+	if err := json.Unmarshal(data, &plan); err != nil {
+		return nil, err
+	}
+	return &plan, nil
+}
+
+// Changes returns the managed resources the plan actually changes
+func (p *Plan) Changes() []ResourceChange {
+	var changes []ResourceChange
+	for _, rc := range p.ResourceChanges {
+		action := rc.Action()
+		if rc.Mode == "data" || action == "no-op" || action == "read" {
+			continue
+		}
+		changes = append(changes, ResourceChange{Address: rc.Address, Action: action})
+	}
+	return changes
+}
+
+// Summary counts the plan's changes the same way Terraform does (a replace
+// counts as one add and one destroy)
+func (p *Plan) Summary() ChangeSummary {
+	summary := ChangeSummary{Operation: "plan"}
+	for _, c := range p.Changes() {
+		switch c.Action {
+		case "create":
+			summary.Add++
+		case "update":
+			summary.Change++
+		case "delete":
+			summary.Remove++
+		case "replace":
+			summary.Add++
+			summary.Remove++
+		}
+	}
+	return summary
+}
+
+// SensitiveVariables returns the sorted names of the plan's root module
+// variables that are declared sensitive
+func (p *Plan) SensitiveVariables() []string {
+	var names []string
+	for name := range p.Variables {
+		if p.Configuration.RootModule.Variables[name].Sensitive {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// SavePlan stores a binary plan file and its JSON rendering under
+// versions/<version>/ and removes the local plan file. The values of
+// sensitive variables are dropped from the JSON unless keepSensitive is set;
+// the binary plan file cannot be redacted and always holds them.
+func SavePlan(store SnapshotStore, version, planFile string, planJSON []byte, keepSensitive bool) error {
+	f, err := os.Open(planFile)
+	if err != nil {
+		return err
+	}
+	err = store.Put("versions/"+version+"/"+PlanFileName, f)
+	f.Close()
+	if err != nil {
+		return err
+	}
+
+	if !keepSensitive {
+		if planJSON, err = redactPlanJSON(planJSON); err != nil {
+			return err
+		}
+	}
+	if err := store.Put("versions/"+version+"/"+PlanJSONFileName, bytes.NewReader(planJSON)); err != nil {
+		return err
+	}
+	return os.Remove(planFile)
+}
+
+// redactPlanJSON drops the values of sensitive root module variables from
+// the variables block of a plan's JSON rendering. Plans without sensitive
+// values are returned unchanged.
+func redactPlanJSON(data []byte) ([]byte, error) {
+	plan, err := ParsePlan(data)
+	if err != nil {
+		return nil, err
+	}
+	var doc map[string]json.RawMessage
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	var variables map[string]map[string]json.RawMessage
+	if raw, ok := doc["variables"]; ok {
+		if err := json.Unmarshal(raw, &variables); err != nil {
+			return nil, err
+		}
+	}
+
+	redacted := false
+	for _, name := range plan.SensitiveVariables() {
+		if _, ok := variables[name]["value"]; ok {
+			delete(variables[name], "value")
+			redacted = true
+		}
+	}
+	if !redacted {
+		return data, nil
+	}
+	if doc["variables"], err = json.Marshal(variables); err != nil {
+		return nil, err
+	}
+	return json.Marshal(doc)
+}
+
+// LoadVersionPlan reads the stored plan JSON of a version
+func LoadVersionPlan(store SnapshotStore, version string) (*Plan, error) {
+	r, err := store.Get("versions/" + version + "/" + PlanJSONFileName)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return ParsePlan(data)
+}
@@ -0,0 +1,71 @@
+package helper
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testPlanJSON = `{"format_version":"1.2","variables":{"region":{"value":"eu-west-1"},"password":{"value":"hunter2"}},` +
+	`"configuration":{"root_module":{"variables":{"region":{},"password":{"sensitive":true}}}}}`
+
+func TestRedactPlanJSON(t *testing.T) {
+	redacted, err := redactPlanJSON([]byte(testPlanJSON))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(redacted, []byte("hunter2")) {
+		t.Errorf("sensitive value kept: %s", redacted)
+	}
+	plan, err := ParsePlan(redacted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := plan.Variables["password"]; !ok {
+		t.Error("sensitive variable dropped instead of its value")
+	}
+	if got := string(plan.Variables["region"].Value); got != `"eu-west-1"` {
+		t.Errorf("region = %s, want it kept", got)
+	}
+	if got := plan.SensitiveVariables(); len(got) != 1 || got[0] != "password" {
+		t.Errorf("SensitiveVariables = %v after redacting", got)
+	}
+
+	// Redacting is idempotent and leaves plans without sensitive values as they are
+	again, err := redactPlanJSON(redacted)
+	if err != nil || !bytes.Equal(again, redacted) {
+		t.Errorf("redacting twice changed the plan: %s, %v", again, err)
+	}
+	plain := `{"variables":{"region":{"value":"eu-west-1"}}}`
+	if got, err := redactPlanJSON([]byte(plain)); err != nil || string(got) != plain {
+		t.Errorf("plan without sensitive variables rewritten as %s, %v", got, err)
+	}
+	if _, err := redactPlanJSON([]byte("{not json")); err == nil {
+		t.Error("invalid plan JSON accepted")
+	}
+}
+
+func TestSavePlan(t *testing.T) {
+	for _, keepSensitive := range []bool{false, true} {
+		store := &FileStore{Root: t.TempDir()}
+		planFile := filepath.Join(t.TempDir(), "plan.tfplan")
+		if err := os.WriteFile(planFile, []byte("binary plan"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := SavePlan(store, "v1", planFile, []byte(testPlanJSON), keepSensitive); err != nil {
+			t.Fatal(err)
+		}
+		stored := readKey(t, store, "versions/v1/"+PlanJSONFileName)
+		if strings.Contains(string(stored), "hunter2") != keepSensitive {
+			t.Errorf("keepSensitive=%v: stored plan.json %s", keepSensitive, stored)
+		}
+		if got := string(readKey(t, store, "versions/v1/"+PlanFileName)); got != "binary plan" {
+			t.Errorf("stored plan.tfplan = %q", got)
+		}
+		if _, err := os.Stat(planFile); !os.IsNotExist(err) {
+			t.Error("local plan file not removed")
+		}
+	}
+}
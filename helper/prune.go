@@ -0,0 +1,183 @@
+package helper
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RetentionPolicy selects the versions kept by 'cloudtm prune'. A version is
+// kept when any rule selects it.
+type RetentionPolicy struct {
+	KeepLast    int           // the newest N versions
+	KeepWithin  time.Duration // versions created within the duration before now
+	KeepDaily   int           // the newest version of each of the last N days with versions
+	KeepWeekly  int           // the newest version of each of the last N ISO weeks with versions
+	KeepMonthly int           // the newest version of each of the last N months with versions
+}
+
+// IsEmpty reports whether the policy keeps nothing by itself
+func (p RetentionPolicy) IsEmpty() bool {
+	return p.KeepLast == 0 && p.KeepWithin == 0 && p.KeepDaily == 0 && p.KeepWeekly == 0 && p.KeepMonthly == 0
+}
+
+// PruneDecision is the outcome of a retention policy for one version
+type PruneDecision struct {
+	Meta    *Metadata
+	Keep    bool
+	Reasons []string
+}
+
+// ParseRetentionDuration parses durations such as "30d", "2w", "12h" or "90m".
+// Days and weeks are not supported by time.ParseDuration.
+func ParseRetentionDuration(s string) (time.Duration, error) {
+	for suffix, unit := range map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour} {
+		if n, ok := strings.CutSuffix(s, suffix); ok {
+			count, err := strconv.Atoi(n)
+			if err != nil || count < 0 {
+				return 0, fmt.Errorf("invalid duration %q", s)
+			}
+			return time.Duration(count) * unit, nil
+		}
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid duration %q (use e.g. 30d, 2w or 12h)", s)
+	}
+	return d, nil
+}
+
+// ApplyRetention decides which versions to keep. protected maps versions that
+// must never be deleted (current, rollback) to the reason; pinned versions and
+// versions with a pinned tag are always kept. Decisions are returned newest first.
+func ApplyRetention(metas []*Metadata, policy RetentionPolicy, protected map[string]string, now time.Time) []PruneDecision {
+	sorted := append([]*Metadata{}, metas...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return VersionNumber(sorted[i].Version) > VersionNumber(sorted[j].Version)
+	})
+
+	buckets := []struct {
+		name  string
+		limit int
+		key   func(time.Time) string
+		seen  map[string]bool
+	}{
+		{"daily", policy.KeepDaily, func(t time.Time) string { return t.Format("2006-01-02") }, map[string]bool{}},
+		{"weekly", policy.KeepWeekly, func(t time.Time) string {
+			year, week := t.ISOWeek()
+			return fmt.Sprintf("%d-W%02d", year, week)
+		}, map[string]bool{}},
+		{"monthly", policy.KeepMonthly, func(t time.Time) string { return t.Format("2006-01") }, map[string]bool{}},
+	}
+
+	decisions := make([]PruneDecision, 0, len(sorted))
+	for i, meta := range sorted {
+		d := PruneDecision{Meta: meta}
+		if reason, ok := protected[meta.Version]; ok {
+			d.Reasons = append(d.Reasons, reason)
+		}
+		if meta.Pinned {
+			d.Reasons = append(d.Reasons, "pinned")
+		}
+		for _, t := range meta.Tags {
+			if t.Pinned {
+				d.Reasons = append(d.Reasons, "pinned tag "+t.Name)
+			}
+		}
+		if i < policy.KeepLast {
+			d.Reasons = append(d.Reasons, "last")
+		}
+
+		if t, err := meta.Time(); err == nil {
+			t = t.UTC()
+			if policy.KeepWithin > 0 && now.Sub(t) <= policy.KeepWithin {
+				d.Reasons = append(d.Reasons, "within")
+			}
+			// Versions are visited newest first, so the first one seen in a
+			// period is that period's newest version
+			for _, b := range buckets {
+				key := b.key(t)
+				if len(b.seen) < b.limit && !b.seen[key] {
+					b.seen[key] = true
+					d.Reasons = append(d.Reasons, b.name)
+				}
+			}
+		}
+
+		d.Keep = len(d.Reasons) > 0
+		decisions = append(decisions, d)
+	}
+	return decisions
+}
+
+// DeleteVersion removes a version's snapshot files and its metadata. Blobs in
+// objects/ are left for GarbageCollect since other versions may share them.
+// The metadata is removed last so an interrupted delete leaves a version that
+// fsck reports instead of orphaned files nobody knows about.
+func DeleteVersion(store SnapshotStore, version string) error {
+	keys, err := store.List("versions/" + version + "/")
+	if err != nil {
+		return err
+	}
+	for _, key := range append(keys, "meta/"+version+".json") {
+		if err := store.Delete(key); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
+
+// UnreferencedObjects returns the blobs in objects/ that no version's manifest
+// references. It fails if any manifest cannot be read, since its blobs would
+// otherwise be collected.
+func UnreferencedObjects(store SnapshotStore) ([]string, error) {
+	versions, err := SnapshotVersions(store)
+	if err != nil {
+		return nil, err
+	}
+
+	referenced := make(map[string]bool)
+	for _, v := range versions {
+		manifest, err := ReadManifest(store, v)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range manifest.Files {
+			referenced[f.SHA256] = true
+		}
+	}
+
+	objects, err := ListObjects(store)
+	if err != nil {
+		return nil, err
+	}
+	var unreferenced []string
+	for hash := range objects {
+		if !referenced[hash] {
+			unreferenced = append(unreferenced, hash)
+		}
+	}
+	sort.Strings(unreferenced)
+	return unreferenced, nil
+}
+
+// GarbageCollect deletes every blob no version references and returns their hashes
+func GarbageCollect(store SnapshotStore) ([]string, error) {
+	unreferenced, err := UnreferencedObjects(store)
+	if err != nil {
+		return nil, err
+	}
+	for i, hash := range unreferenced {
+		if err := store.Delete(objectKey(hash)); err != nil {
+			return unreferenced[:i], err
+		}
+	}
+	return unreferenced, nil
+}
@@ -0,0 +1,222 @@
+package helper
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testMetas returns versions v1..vN created at the given times, oldest first
+func testMetas(times ...string) []*Metadata {
+	var metas []*Metadata
+	for i, t := range times {
+		metas = append(metas, &Metadata{Version: fmt.Sprintf("v%d", i+1), Timestamp: t})
+	}
+	return metas
+}
+
+// kept returns the kept versions of decisions, newest first
+func kept(decisions []PruneDecision) []string {
+	versions := []string{}
+	for _, d := range decisions {
+		if d.Keep {
+			versions = append(versions, d.Meta.Version)
+		}
+	}
+	return versions
+}
+
+func TestApplyRetention(t *testing.T) {
+	now := time.Date(2025, 11, 27, 12, 0, 0, 0, time.UTC)
+	// Two versions on each of three days (Mon 2025-11-17, Mon 2025-11-24,
+	// Thu 2025-11-27) spanning two ISO weeks, and one in October
+	metas := testMetas(
+		"2025-10-02T09:00:00Z", // v1
+		"2025-11-17T09:00:00Z", // v2
+		"2025-11-17T18:00:00Z", // v3
+		"2025-11-24T09:00:00Z", // v4
+		"2025-11-24T18:00:00Z", // v5
+		"2025-11-27T09:00:00Z", // v6
+		"2025-11-27T11:00:00Z", // v7
+	)
+
+	tests := []struct {
+		name   string
+		policy RetentionPolicy
+		want   []string
+	}{
+		{"empty policy", RetentionPolicy{}, []string{}},
+		{"keep last", RetentionPolicy{KeepLast: 3}, []string{"v7", "v6", "v5"}},
+		{"keep last more than exist", RetentionPolicy{KeepLast: 10}, []string{"v7", "v6", "v5", "v4", "v3", "v2", "v1"}},
+		{"keep within", RetentionPolicy{KeepWithin: 4 * 24 * time.Hour}, []string{"v7", "v6", "v5", "v4"}},
+		{"keep daily", RetentionPolicy{KeepDaily: 2}, []string{"v7", "v5"}},
+		{"keep weekly", RetentionPolicy{KeepWeekly: 2}, []string{"v7", "v3"}},
+		{"keep monthly", RetentionPolicy{KeepMonthly: 2}, []string{"v7", "v1"}},
+		{"rules combine", RetentionPolicy{KeepLast: 1, KeepDaily: 3, KeepMonthly: 2}, []string{"v7", "v5", "v3", "v1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := kept(ApplyRetention(metas, tt.policy, nil, now))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("kept %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyRetentionProtects(t *testing.T) {
+	now := time.Date(2025, 11, 27, 12, 0, 0, 0, time.UTC)
+	metas := testMetas(
+		"2025-11-20T09:00:00Z", // v1: rollback
+		"2025-11-21T09:00:00Z", // v2: pinned
+		"2025-11-22T09:00:00Z", // v3: pinned tag
+		"2025-11-23T09:00:00Z", // v4: unpinned tag
+		"2025-11-24T09:00:00Z", // v5: current
+		"2025-11-25T09:00:00Z", // v6
+	)
+	metas[1].Pinned = true
+	metas[2].Tags = []Tag{{Name: "release", Pinned: true}}
+	metas[3].Tags = []Tag{{Name: "scratch"}}
+	protected := map[string]string{"v5": "current", "v1": "rollback"}
+
+	decisions := ApplyRetention(metas, RetentionPolicy{KeepLast: 1}, protected, now)
+
+	reasons := make(map[string][]string)
+	for _, d := range decisions {
+		reasons[d.Meta.Version] = d.Reasons
+		if d.Keep != (len(d.Reasons) > 0) {
+			t.Errorf("%s: Keep=%v with reasons %v", d.Meta.Version, d.Keep, d.Reasons)
+		}
+	}
+	want := map[string][]string{
+		"v6": {"last"},
+		"v5": {"current"},
+		"v4": nil,
+		"v3": {"pinned tag release"},
+		"v2": {"pinned"},
+		"v1": {"rollback"},
+	}
+	if !reflect.DeepEqual(reasons, want) {
+		t.Errorf("reasons %v, want %v", reasons, want)
+	}
+
+	var order []string
+	for _, d := range decisions {
+		order = append(order, d.Meta.Version)
+	}
+	if strings.Join(order, " ") != "v6 v5 v4 v3 v2 v1" {
+		t.Errorf("decisions are not newest first: %v", order)
+	}
+}
+
+func TestApplyRetentionIgnoresInvalidTimes(t *testing.T) {
+	metas := testMetas("not a time", "2025-11-27T09:00:00Z")
+	got := kept(ApplyRetention(metas, RetentionPolicy{KeepDaily: 5}, nil, time.Now()))
+	if !reflect.DeepEqual(got, []string{"v2"}) {
+		t.Errorf("kept %v, want only v2 (v1 has no valid time for a daily bucket)", got)
+	}
+}
+
+func TestParseRetentionDuration(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    time.Duration
+		wantErr bool
+	}{
+		{"30d", 30 * 24 * time.Hour, false},
+		{"2w", 14 * 24 * time.Hour, false},
+		{"12h", 12 * time.Hour, false},
+		{"90m", 90 * time.Minute, false},
+		{"-1d", 0, true},
+		{"xd", 0, true},
+		{"-5h", 0, true},
+		{"soon", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseRetentionDuration(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseRetentionDuration(%q) = %v, %v; want %v, error %v", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+// snapshotFiles stores files as a version of store
+func snapshotFiles(t *testing.T, store SnapshotStore, version string, files map[string]string) {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := CreateSnapshot(store, dir, version, ""); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGarbageCollect(t *testing.T) {
+	store := &FileStore{Root: t.TempDir()}
+	snapshotFiles(t, store, "v1", map[string]string{"main.tf": "shared", "old.tf": "only in v1"})
+	snapshotFiles(t, store, "v2", map[string]string{"main.tf": "shared", "new.tf": "only in v2"})
+
+	if unreferenced, err := GarbageCollect(store); err != nil || len(unreferenced) != 0 {
+		t.Fatalf("GarbageCollect with every blob referenced = %v, %v", unreferenced, err)
+	}
+
+	if err := DeleteVersion(store, "v1"); err != nil {
+		t.Fatal(err)
+	}
+	if exists, _ := VersionExists(store, "v1"); exists {
+		t.Fatal("v1 still exists after DeleteVersion")
+	}
+
+	collected, err := GarbageCollect(store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{HashBytes([]byte("only in v1"))}; !reflect.DeepEqual(collected, want) {
+		t.Errorf("collected %v, want only the blob unique to v1 %v", collected, want)
+	}
+
+	objects, err := ListObjects(store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var remaining []string
+	for hash := range objects {
+		remaining = append(remaining, hash)
+	}
+	sort.Strings(remaining)
+	want := []string{HashBytes([]byte("shared")), HashBytes([]byte("only in v2"))}
+	sort.Strings(want)
+	if !reflect.DeepEqual(remaining, want) {
+		t.Errorf("remaining objects %v, want %v", remaining, want)
+	}
+
+	if _, err := MaterializeFiles(store, "v2", t.TempDir(), nil); err != nil {
+		t.Errorf("v2 cannot be read after garbage collection: %v", err)
+	}
+}
+
+func TestGarbageCollectFailsOnUnreadableManifest(t *testing.T) {
+	store := &FileStore{Root: t.TempDir()}
+	snapshotFiles(t, store, "v1", map[string]string{"main.tf": "content"})
+	if err := store.Put(manifestKey("v1"), strings.NewReader("{not json")); err != nil {
+		t.Fatal(err)
+	}
+
+	collected, err := GarbageCollect(store)
+	if err == nil {
+		t.Fatalf("GarbageCollect with a corrupt manifest collected %v, want an error", collected)
+	}
+	if _, err := GetObject(store, HashBytes([]byte("content"))); errors.Is(err, fs.ErrNotExist) {
+		t.Error("blob referenced by the unreadable manifest was deleted")
+	}
+}
@@ -0,0 +1,156 @@
+package helper
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// configSuffixes are the files in-place rollback treats as Terraform
+// configuration: restored from the version and removed when the version does
+// not have them
+var configSuffixes = []string{".tf", ".tf.json", ".tfvars", ".tfvars.json", ".terraform.lock.hcl"}
+
+// IsConfigFile reports whether a project path is Terraform configuration
+func IsConfigFile(path string) bool {
+	name := filepath.Base(path)
+	for _, suffix := range configSuffixes {
+		if strings.HasSuffix(name, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// RestoreConfig writes the files of a version, except its state, into the
+// project directory and removes Terraform configuration files the version
+// does not have. The live state is left untouched. It returns the paths
+// written and removed.
+func RestoreConfig(store SnapshotStore, version, projectDir string) ([]string, []string, error) {
+	current, err := BackupProject(projectDir)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	written, err := MaterializeFiles(store, version, projectDir, IsStateFile)
+	if err != nil {
+		return written, nil, err
+	}
+
+	inVersion := make(map[string]bool, len(written))
+	for _, p := range written {
+		inVersion[p] = true
+	}
+	var removed []string
+	for p := range current {
+		if IsConfigFile(p) && !inVersion[p] {
+			if err := os.Remove(filepath.Join(projectDir, filepath.FromSlash(p))); err != nil {
+				return written, removed, err
+			}
+			removed = append(removed, p)
+		}
+	}
+	sort.Strings(removed)
+	return written, removed, nil
+}
+
+// ProjectBackup holds the project files (except state) so an in-place
+// operation can be undone
+type ProjectBackup map[string]backupFile
+
+type backupFile struct {
+	data []byte
+	mode os.FileMode
+}
+
+// BackupProject reads every project file a snapshot would capture, except state files
+func BackupProject(projectDir string) (ProjectBackup, error) {
+	backup := make(ProjectBackup)
+	err := WalkProjectFiles(projectDir, SnapshotExcludeDirs, SnapshotExcludeFiles, SnapshotExcludePatterns, func(src, relPath string, info os.FileInfo) error {
+		relPath = filepath.ToSlash(relPath)
+		if IsStateFile(relPath) {
+			return nil
+		}
+		data, err := os.ReadFile(src)
+		if err != nil {
+			return err
+		}
+		backup[relPath] = backupFile{data: data, mode: info.Mode().Perm()}
+		return nil
+	})
+	return backup, err
+}
+
+// Restore writes the backed up files back and removes configuration files
+// that were added since the backup was taken
+func (b ProjectBackup) Restore(projectDir string) error {
+	current, err := BackupProject(projectDir)
+	if err != nil {
+		return err
+	}
+	for p := range current {
+		if _, ok := b[p]; !ok && IsConfigFile(p) {
+			if err := os.Remove(filepath.Join(projectDir, filepath.FromSlash(p))); err != nil {
+				return err
+			}
+		}
+	}
+
+	for p, f := range b {
+		target := filepath.Join(projectDir, filepath.FromSlash(p))
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return err
+		}
+		if err := WriteFileAtomic(target, f.data, f.mode); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PromoteRollback copies the configuration and state of an applied rollback
+// directory into the project directory and removes Terraform configuration
+// files the rollback does not have. The copy of the version's metadata kept
+// in the rollback directory is skipped. It returns the paths written and
+// removed.
+func PromoteRollback(rollbackDir, projectDir, version string) ([]string, []string, error) {
+	current, err := BackupProject(projectDir)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var written []string
+	inRollback := make(map[string]bool)
+	err = WalkProjectFiles(rollbackDir, SnapshotExcludeDirs, SnapshotExcludeFiles, SnapshotExcludePatterns, func(src, relPath string, info os.FileInfo) error {
+		relPath = filepath.ToSlash(relPath)
+		if relPath == version+".json" {
+			return nil
+		}
+		target := filepath.Join(projectDir, filepath.FromSlash(relPath))
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return err
+		}
+		if err := CopyFile(src, target); err != nil {
+			return err
+		}
+		inRollback[relPath] = true
+		written = append(written, relPath)
+		return nil
+	})
+	if err != nil {
+		return written, nil, err
+	}
+
+	var removed []string
+	for p := range current {
+		if IsConfigFile(p) && !inRollback[p] {
+			if err := os.Remove(filepath.Join(projectDir, filepath.FromSlash(p))); err != nil {
+				return written, removed, err
+			}
+			removed = append(removed, p)
+		}
+	}
+	sort.Strings(removed)
+	return written, removed, nil
+}
@@ -0,0 +1,318 @@
+package helper
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// S3Store stores objects in an S3-compatible bucket (AWS S3, MinIO, ...).
+// Requests use path-style addressing and AWS Signature Version 4.
+type S3Store struct {
+	Endpoint     string // e.g. https://s3.us-east-1.amazonaws.com or http://localhost:9000
+	Region       string
+	Bucket       string
+	Prefix       string
+	AccessKey    string
+	SecretKey    string
+	SessionToken string
+	Client       *http.Client
+}
+
+// NewS3StoreFromURL creates a store from s3://bucket/prefix. The endpoint,
+// region and credentials come from the environment:
+//
+//	CLOUDTM_S3_ENDPOINT (or AWS_ENDPOINT_URL_S3, AWS_ENDPOINT_URL)
+//	AWS_REGION (or AWS_DEFAULT_REGION), defaults to us-east-1
+//	AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY, AWS_SESSION_TOKEN
+func NewS3StoreFromURL(location string) (*S3Store, error) {
+	u, err := url.Parse(location)
+	if err != nil {
+		return nil, err
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("missing bucket in %q", location)
+	}
+
+	region := firstEnv("AWS_REGION", "AWS_DEFAULT_REGION")
+	if region == "" {
+		region = "us-east-1"
+	}
+	endpoint := firstEnv("CLOUDTM_S3_ENDPOINT", "AWS_ENDPOINT_URL_S3", "AWS_ENDPOINT_URL")
+	if endpoint == "" {
+		endpoint = "https://s3." + region + ".amazonaws.com"
+	}
+
+	store := &S3Store{
+		Endpoint:     strings.TrimSuffix(endpoint, "/"),
+		Region:       region,
+		Bucket:       u.Host,
+		Prefix:       strings.Trim(u.Path, "/"),
+		AccessKey:    os.Getenv("AWS_ACCESS_KEY_ID"),
+		SecretKey:    os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		SessionToken: os.Getenv("AWS_SESSION_TOKEN"),
+		Client:       &http.Client{Timeout: 5 * time.Minute},
+	}
+	if store.AccessKey == "" || store.SecretKey == "" {
+		return nil, fmt.Errorf("AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY must be set for %s", location)
+	}
+	return store, nil
+}
+
+func firstEnv(names ...string) string {
+	for _, name := range names {
+		if value := os.Getenv(name); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+func (s *S3Store) String() string {
+	if s.Prefix == "" {
+		return "s3://" + s.Bucket
+	}
+	return "s3://" + s.Bucket + "/" + s.Prefix
+}
+
+func (s *S3Store) objectKey(key string) string {
+	if s.Prefix == "" {
+		return key
+	}
+	return s.Prefix + "/" + key
+}
+
+// Put uploads the object
+func (s *S3Store) Put(key string, r io.Reader) error {
+	body, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	resp, err := s.do(http.MethodPut, s.objectKey(key), nil, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return s.checkResponse(resp, key)
+}
+
+// Get downloads the object
+func (s *S3Store) Get(key string) (io.ReadCloser, error) {
+	resp, err := s.do(http.MethodGet, s.objectKey(key), nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkResponse(resp, key); err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// Delete removes the object
+func (s *S3Store) Delete(key string) error {
+	resp, err := s.do(http.MethodDelete, s.objectKey(key), nil, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return s.checkResponse(resp, key)
+}
+
+// listBucketResult is the response of ListObjectsV2
+type listBucketResult struct {
+	Contents []struct {
+		Key string `xml:"Key"`
+	} `xml:"Contents"`
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+}
+
+// List pages through ListObjectsV2 for the prefix
+func (s *S3Store) List(prefix string) ([]string, error) {
+	var keys []string
+	token := ""
+	for {
+		query := url.Values{}
+		query.Set("list-type", "2")
+		query.Set("prefix", s.objectKey(prefix))
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+
+		resp, err := s.do(http.MethodGet, "", query, nil)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.checkResponse(resp, prefix); err != nil {
+			resp.Body.Close()
+			return nil, err
+		}
+
+		var result listBucketResult
+		err = xml.NewDecoder(resp.Body).Decode(&result)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, c := range result.Contents {
+			key := c.Key
+			if s.Prefix != "" {
+				key = strings.TrimPrefix(key, s.Prefix+"/")
+			}
+			keys = append(keys, key)
+		}
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			break
+		}
+		token = result.NextContinuationToken
+	}
+
+	sort.Strings(keys)
+	return keys, nil
+}
+
+func (s *S3Store) checkResponse(resp *http.Response, key string) error {
+	if resp.StatusCode == http.StatusNotFound {
+		return &fs.PathError{Op: "get", Path: s.String() + "/" + key, Err: fs.ErrNotExist}
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("%s %s: %s: %s", resp.Request.Method, key, resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+// do sends a signed request for the object key (or the bucket when key is empty)
+func (s *S3Store) do(method, key string, query url.Values, body []byte) (*http.Response, error) {
+	u, err := url.Parse(s.Endpoint)
+	if err != nil {
+		return nil, err
+	}
+	u.Path = "/" + s.Bucket
+	if key != "" {
+		u.Path += "/" + key
+	}
+	u.RawPath = awsEscapePath(u.Path)
+	u.RawQuery = awsCanonicalQuery(query)
+
+	req, err := http.NewRequest(method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	s.sign(req, u, body, time.Now().UTC())
+	return s.Client.Do(req)
+}
+
+// sign adds AWS Signature Version 4 headers to the request
+func (s *S3Store) sign(req *http.Request, u *url.URL, body []byte, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	payloadHash := sha256Hex(body)
+
+	req.Header.Set("x-amz-date", amzDate)
+	req.Header.Set("x-amz-content-sha256", payloadHash)
+	if s.SessionToken != "" {
+		req.Header.Set("x-amz-security-token", s.SessionToken)
+	}
+
+	headerNames := []string{"host", "x-amz-content-sha256", "x-amz-date"}
+	if s.SessionToken != "" {
+		headerNames = append(headerNames, "x-amz-security-token")
+	}
+	var canonicalHeaders strings.Builder
+	for _, name := range headerNames {
+		value := req.Header.Get(name)
+		if name == "host" {
+			value = u.Host
+		}
+		canonicalHeaders.WriteString(name + ":" + strings.TrimSpace(value) + "\n")
+	}
+	signedHeaders := strings.Join(headerNames, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		u.RawPath,
+		u.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := date + "/" + s.Region + "/s3/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + sha256Hex([]byte(canonicalRequest))
+
+	key := hmacSHA256([]byte("AWS4"+s.SecretKey), date)
+	key = hmacSHA256(key, s.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.AccessKey, scope, signedHeaders, signature))
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// awsEscape percent-encodes everything except the RFC 3986 unreserved characters
+func awsEscape(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if ('A' <= c && c <= 'Z') || ('a' <= c && c <= 'z') || ('0' <= c && c <= '9') ||
+			c == '-' || c == '_' || c == '.' || c == '~' {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+func awsEscapePath(p string) string {
+	segments := strings.Split(p, "/")
+	for i, segment := range segments {
+		segments[i] = awsEscape(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+func awsCanonicalQuery(query url.Values) string {
+	if len(query) == 0 {
+		return ""
+	}
+	names := make([]string, 0, len(query))
+	for name := range query {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var parts []string
+	for _, name := range names {
+		for _, value := range query[name] {
+			parts = append(parts, awsEscape(name)+"="+awsEscape(value))
+		}
+	}
+	return strings.Join(parts, "&")
+}
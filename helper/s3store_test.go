@@ -0,0 +1,301 @@
+package helper
+
+import (
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+const (
+	testAccessKey = "AKIDEXAMPLE"
+	testSecretKey = "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY"
+	testRegion    = "us-east-1"
+	testBucket    = "bucket"
+)
+
+// fakeS3 is a minimal in-memory stand-in for an S3-compatible server (e.g.
+// MinIO) that checks the signature of every request
+type fakeS3 struct {
+	t        *testing.T
+	pageSize int
+
+	mu      sync.Mutex
+	objects map[string][]byte
+	lists   int
+}
+
+func newFakeS3(t *testing.T) (*fakeS3, *httptest.Server) {
+	f := &fakeS3{t: t, pageSize: 2, objects: make(map[string][]byte)}
+	server := httptest.NewServer(f)
+	t.Cleanup(server.Close)
+	return f, server
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	if err := verifySignature(r, body); err != nil {
+		f.t.Errorf("%s %s: %v", r.Method, r.RequestURI, err)
+		http.Error(w, "SignatureDoesNotMatch", http.StatusForbidden)
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if r.URL.Path == "/"+testBucket {
+		f.list(w, r.URL.Query())
+		return
+	}
+	key, ok := strings.CutPrefix(r.URL.Path, "/"+testBucket+"/")
+	if !ok {
+		http.Error(w, "NoSuchBucket", http.StatusNotFound)
+		return
+	}
+	switch r.Method {
+	case http.MethodPut:
+		f.objects[key] = body
+	case http.MethodGet:
+		data, ok := f.objects[key]
+		if !ok {
+			http.Error(w, "<Error><Code>NoSuchKey</Code></Error>", http.StatusNotFound)
+			return
+		}
+		w.Write(data)
+	case http.MethodDelete:
+		delete(f.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// list answers ListObjectsV2 with pageSize keys per page
+func (f *fakeS3) list(w http.ResponseWriter, query url.Values) {
+	f.lists++
+	if query.Get("list-type") != "2" {
+		http.Error(w, "unsupported list type", http.StatusBadRequest)
+		return
+	}
+	var keys []string
+	for key := range f.objects {
+		if strings.HasPrefix(key, query.Get("prefix")) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	start := 0
+	if token := query.Get("continuation-token"); token != "" {
+		start, _ = strconv.Atoi(token)
+	}
+	end := start + f.pageSize
+	if end > len(keys) {
+		end = len(keys)
+	}
+
+	var result listBucketResult
+	for _, key := range keys[start:end] {
+		result.Contents = append(result.Contents, struct {
+			Key string `xml:"Key"`
+		}{Key: key})
+	}
+	if end < len(keys) {
+		result.IsTruncated = true
+		result.NextContinuationToken = strconv.Itoa(end)
+	}
+	xml.NewEncoder(w).Encode(result)
+}
+
+// verifySignature recomputes the SigV4 signature from the request as
+// received: the escaped path and query sent on the wire must be the ones
+// that were signed
+func verifySignature(r *http.Request, body []byte) error {
+	auth := r.Header.Get("Authorization")
+	var credential, signedHeaders, signature string
+	for _, part := range strings.Split(strings.TrimPrefix(auth, "AWS4-HMAC-SHA256 "), ", ") {
+		name, value, _ := strings.Cut(part, "=")
+		switch name {
+		case "Credential":
+			credential = value
+		case "SignedHeaders":
+			signedHeaders = value
+		case "Signature":
+			signature = value
+		}
+	}
+	if !strings.HasPrefix(credential, testAccessKey+"/") {
+		return fmt.Errorf("unexpected credential %q", credential)
+	}
+	if got := r.Header.Get("x-amz-content-sha256"); got != sha256Hex(body) {
+		return fmt.Errorf("payload hash %s does not match the body", got)
+	}
+
+	path, query, _ := strings.Cut(r.RequestURI, "?")
+	var headers strings.Builder
+	for _, name := range strings.Split(signedHeaders, ";") {
+		value := r.Header.Get(name)
+		if name == "host" {
+			value = r.Host
+		}
+		headers.WriteString(name + ":" + value + "\n")
+	}
+	canonicalRequest := strings.Join([]string{r.Method, path, query, headers.String(), signedHeaders, sha256Hex(body)}, "\n")
+
+	amzDate := r.Header.Get("x-amz-date")
+	scope := strings.TrimPrefix(credential, testAccessKey+"/")
+	date := strings.Split(scope, "/")[0]
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + sha256Hex([]byte(canonicalRequest))
+	key := hmacSHA256([]byte("AWS4"+testSecretKey), date)
+	key = hmacSHA256(key, testRegion)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	if expected := hex.EncodeToString(hmacSHA256(key, stringToSign)); signature != expected {
+		return fmt.Errorf("signature mismatch for canonical request:\n%s", canonicalRequest)
+	}
+	return nil
+}
+
+func newTestS3Store(server *httptest.Server, prefix string) *S3Store {
+	return &S3Store{
+		Endpoint:  server.URL,
+		Region:    testRegion,
+		Bucket:    testBucket,
+		Prefix:    prefix,
+		AccessKey: testAccessKey,
+		SecretKey: testSecretKey,
+		Client:    server.Client(),
+	}
+}
+
+func TestS3StorePutGetDelete(t *testing.T) {
+	fake, server := newFakeS3(t)
+	store := newTestS3Store(server, "team/app")
+
+	keys := []string{
+		"objects/ab/abcdef",
+		"versions/v1/main.tf",
+		"versions/v1/a b+c&d=e.tf",
+		"versions/v1/ünïcode~(1).tfvars",
+	}
+	for _, key := range keys {
+		if err := store.Put(key, strings.NewReader("content of "+key)); err != nil {
+			t.Fatalf("Put(%q): %v", key, err)
+		}
+		if _, ok := fake.objects["team/app/"+key]; !ok {
+			t.Errorf("Put(%q) stored under an unexpected key; objects: %v", key, fake.objects)
+		}
+	}
+
+	for _, key := range keys {
+		r, err := store.Get(key)
+		if err != nil {
+			t.Fatalf("Get(%q): %v", key, err)
+		}
+		data, _ := io.ReadAll(r)
+		r.Close()
+		if string(data) != "content of "+key {
+			t.Errorf("Get(%q) = %q", key, data)
+		}
+	}
+
+	if err := store.Delete(keys[0]); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := store.Get(keys[0]); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Get after Delete: got %v, want fs.ErrNotExist", err)
+	}
+}
+
+func TestS3StoreGetMissing(t *testing.T) {
+	_, server := newFakeS3(t)
+	store := newTestS3Store(server, "")
+
+	_, err := store.Get("versions/v9/manifest.json")
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("got %v, want an error matching fs.ErrNotExist", err)
+	}
+}
+
+func TestS3StoreListPaginates(t *testing.T) {
+	fake, server := newFakeS3(t)
+	store := newTestS3Store(server, "team/app")
+
+	fake.objects["team/app/versions/v1/main.tf"] = nil
+	fake.objects["team/app/versions/v1/plan.json"] = nil
+	fake.objects["team/app/versions/v2/main.tf"] = nil
+	fake.objects["team/app/versions/v2/sp ace+plus.tf"] = nil
+	fake.objects["team/app/versions/v3/main.tf"] = nil
+	fake.objects["team/app/objects/ab/abcdef"] = nil
+	fake.objects["team/other/versions/v1/main.tf"] = nil
+
+	keys, err := store.List("versions/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"versions/v1/main.tf",
+		"versions/v1/plan.json",
+		"versions/v2/main.tf",
+		"versions/v2/sp ace+plus.tf",
+		"versions/v3/main.tf",
+	}
+	if strings.Join(keys, "\n") != strings.Join(want, "\n") {
+		t.Errorf("List = %q, want %q", keys, want)
+	}
+	if fake.lists != 3 {
+		t.Errorf("List made %d requests, want 3 pages of 2 keys", fake.lists)
+	}
+
+	keys, err = store.List("versions/v2/sp ace")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 1 || keys[0] != "versions/v2/sp ace+plus.tf" {
+		t.Errorf("List with special characters in the prefix = %q", keys)
+	}
+}
+
+func TestAWSEscapePath(t *testing.T) {
+	tests := []struct {
+		path, want string
+	}{
+		{"/bucket/versions/v1/main.tf", "/bucket/versions/v1/main.tf"},
+		{"/bucket/a b", "/bucket/a%20b"},
+		{"/bucket/c+d=e&f", "/bucket/c%2Bd%3De%26f"},
+		{"/bucket/unreserved-_.~", "/bucket/unreserved-_.~"},
+		{"/bucket/(1)*!'", "/bucket/%281%29%2A%21%27"},
+		{"/bucket/ü", "/bucket/%C3%BC"},
+		{"/bucket/100%", "/bucket/100%25"},
+	}
+	for _, tt := range tests {
+		if got := awsEscapePath(tt.path); got != tt.want {
+			t.Errorf("awsEscapePath(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestAWSCanonicalQuery(t *testing.T) {
+	tests := []struct {
+		query url.Values
+		want  string
+	}{
+		{nil, ""},
+		{url.Values{"list-type": {"2"}, "prefix": {"versions/"}}, "list-type=2&prefix=versions%2F"},
+		{url.Values{"prefix": {"a b+c"}, "continuation-token": {"1/2=="}, "list-type": {"2"}},
+			"continuation-token=1%2F2%3D%3D&list-type=2&prefix=a%20b%2Bc"},
+	}
+	for _, tt := range tests {
+		if got := awsCanonicalQuery(tt.query); got != tt.want {
+			t.Errorf("awsCanonicalQuery(%v) = %q, want %q", tt.query, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package helper
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// sequence is stored in .cloudtm/sequence.json and records the highest
+// version number ever allocated, so numbers are never reused after pruning
+type sequence struct {
+	Last int `json:"last"`
+}
+
+// NextVersion allocates the next version name (v1, v2, ...). The number is
+// one more than the highest number recorded in sequence.json or used by any
+// version directory or metadata file, and is recorded before it is returned.
+func NextVersion(cloudtmDir string) (string, error) {
+	seqFile := filepath.Join(cloudtmDir, "sequence.json")
+
+	var seq sequence
+	data, err := os.ReadFile(seqFile)
+	if err != nil && !os.IsNotExist(err) {
+		return "", err
+	}
+	if err == nil {
+		if err := json.Unmarshal(data, &seq); err != nil {
+			return "", fmt.Errorf("sequence.json: %w", err)
+		}
+	}
+
+	// Versions created before sequence.json existed, or copied in by pull
+	for _, dir := range []string{"versions", "meta"} {
+		files, err := os.ReadDir(filepath.Join(cloudtmDir, dir))
+		if err != nil && !os.IsNotExist(err) {
+			return "", err
+		}
+		for _, file := range files {
+			if n := VersionNumber(strings.TrimSuffix(file.Name(), ".json")); n > seq.Last {
+				seq.Last = n
+			}
+		}
+	}
+
+	seq.Last++
+	seqJSON, err := json.MarshalIndent(seq, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	if err := WriteFileAtomic(seqFile, seqJSON, 0644); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("v%d", seq.Last), nil
+}
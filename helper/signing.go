@@ -0,0 +1,72 @@
+package helper
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// GenerateSigningKey returns a new ed25519 key pair for signing versions
+func GenerateSigningKey() (ed25519.PublicKey, ed25519.PrivateKey, error) {
+	return ed25519.GenerateKey(rand.Reader)
+}
+
+// EncodeSigningKey encodes a private key (its 32-byte seed) for a key file
+func EncodeSigningKey(key ed25519.PrivateKey) string {
+	return base64.StdEncoding.EncodeToString(key.Seed())
+}
+
+// EncodePublicKey encodes a public key for a .pub file
+func EncodePublicKey(key ed25519.PublicKey) string {
+	return base64.StdEncoding.EncodeToString(key)
+}
+
+// ReadSigningKeyFile reads a private key written by EncodeSigningKey
+func ReadSigningKeyFile(path string) (ed25519.PrivateKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	seed, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil || len(seed) != ed25519.SeedSize {
+		return nil, fmt.Errorf("%s: signing key must be a base64 encoded %d-byte ed25519 seed", path, ed25519.SeedSize)
+	}
+	return ed25519.NewKeyFromSeed(seed), nil
+}
+
+// ReadPublicKeyFile reads a public key written by EncodePublicKey
+func ReadPublicKeyFile(path string) (ed25519.PublicKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("%s: public key must be a base64 encoded %d-byte ed25519 key", path, ed25519.PublicKeySize)
+	}
+	return ed25519.PublicKey(key), nil
+}
+
+// LoadSigningKey returns the configured version signing key, or nil when
+// signing is not enabled. CLOUDTM_SIGNING_KEY_FILE takes precedence over
+// config.json.
+func LoadSigningKey(cfg *Config) (ed25519.PrivateKey, error) {
+	if path := os.Getenv("CLOUDTM_SIGNING_KEY_FILE"); path != "" {
+		return ReadSigningKeyFile(path)
+	}
+	if cfg != nil && cfg.SigningKeyFile != "" {
+		return ReadSigningKeyFile(cfg.SigningKeyFile)
+	}
+	return nil, nil
+}
+
+// PublicKeyID returns a short identifier of a public key, stored with each signature
+func PublicKeyID(key ed25519.PublicKey) string {
+	sum := sha256.Sum256(key)
+	return hex.EncodeToString(sum[:8])
+}
@@ -0,0 +1,297 @@
+package helper
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Paths and patterns that are never part of a snapshot
+var (
+	SnapshotExcludeDirs     = []string{".terraform", ".cloudtm"}
+	SnapshotExcludeFiles    = []string{"terraform.tfstate.backup"}
+	SnapshotExcludePatterns = []string{"*.log", "*.tmp", ".*" + tempMarker + "*"}
+)
+
+// ManifestFileName is the manifest stored in versions/<version>/
+const ManifestFileName = "manifest.json"
+
+// Manifest maps the files of a version to content-addressed blobs in objects/
+type Manifest struct {
+	Files []SnapshotFile `json:"files"`
+}
+
+// SnapshotFile describes a single file of a version
+type SnapshotFile struct {
+	Path   string      `json:"path"`
+	SHA256 string      `json:"sha256,omitempty"`
+	Size   int64       `json:"size"`
+	Mode   os.FileMode `json:"mode"`
+}
+
+// manifestKey returns the store key of a version's manifest
+func manifestKey(version string) string {
+	return "versions/" + version + "/" + ManifestFileName
+}
+
+// legacyPrefix returns the store key prefix of a version stored as a plain
+// tf_configs directory (versions created before content-addressed storage)
+func legacyPrefix(version string) string {
+	return "versions/" + version + "/tf_configs/"
+}
+
+// SnapshotResult summarizes a stored snapshot
+type SnapshotResult struct {
+	Format      string
+	Files       int
+	NewObjects  int
+	Bytes       int64
+	StoredBytes int64
+}
+
+func (r *SnapshotResult) String() string {
+	if r.Format == "manifest" {
+		return fmt.Sprintf("%d files (%d new objects)", r.Files, r.NewObjects)
+	}
+	return fmt.Sprintf("%d files, %s, %d → %d bytes", r.Files, r.Format, r.Bytes, r.StoredBytes)
+}
+
+// CreateSnapshot stores the project directory as a new version. Without
+// compression every file is written once to objects/ by content hash and
+// versions/<version>/manifest.json maps paths to hashes. With gzip or zstd
+// compression the version is stored as a single compressed tar archive.
+func CreateSnapshot(store SnapshotStore, projectDir, version, compression string) (*SnapshotResult, error) {
+	if err := ValidateCompression(compression); err != nil {
+		return nil, err
+	}
+	if compression != "" && compression != CompressionNone {
+		return createArchive(store, projectDir, version, compression)
+	}
+
+	existing, err := ListObjects(store)
+	if err != nil {
+		return nil, err
+	}
+
+	manifest := &Manifest{}
+	result := &SnapshotResult{Format: "manifest"}
+	err = WalkProjectFiles(projectDir, SnapshotExcludeDirs, SnapshotExcludeFiles, SnapshotExcludePatterns, func(src, relPath string, info os.FileInfo) error {
+		data, err := os.ReadFile(src)
+		if err != nil {
+			return err
+		}
+
+		hash, created, err := PutObject(store, data, existing)
+		if err != nil {
+			return err
+		}
+		if created {
+			result.NewObjects++
+			result.StoredBytes += int64(len(data))
+		}
+		result.Bytes += int64(len(data))
+
+		manifest.Files = append(manifest.Files, SnapshotFile{
+			Path:   filepath.ToSlash(relPath),
+			SHA256: hash,
+			Size:   int64(len(data)),
+			Mode:   info.Mode().Perm(),
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	result.Files = len(manifest.Files)
+
+	// The manifest is written last so a version only exists once all its blobs do
+	manifestJSON, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := store.Put(manifestKey(version), bytes.NewReader(manifestJSON)); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// ReadManifest reads a version's manifest. Versions stored in the legacy
+// directory format return an error matching fs.ErrNotExist.
+func ReadManifest(store SnapshotStore, version string) (*Manifest, error) {
+	r, err := store.Get(manifestKey(version))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	var manifest Manifest
+	if err := json.NewDecoder(r).Decode(&manifest); err != nil {
+		return nil, fmt.Errorf("%s manifest: %w", version, err)
+	}
+	return &manifest, nil
+}
+
+// VersionExists reports whether the store holds snapshot files for the version
+func VersionExists(store SnapshotStore, version string) (bool, error) {
+	keys, err := store.List("versions/" + version + "/")
+	if err != nil {
+		return false, err
+	}
+	for _, key := range keys {
+		if versionFormat(version, key) != "" {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// versionFormat returns the storage format a key indicates for a version,
+// or "" when the key is not snapshot content (e.g. plan files)
+func versionFormat(version, key string) string {
+	if key == manifestKey(version) {
+		return "manifest"
+	}
+	for compression := range archiveExtensions {
+		if key == archiveKey(version, compression) {
+			return compression
+		}
+	}
+	if strings.HasPrefix(key, legacyPrefix(version)) {
+		return "legacy"
+	}
+	return ""
+}
+
+// VersionReader gives access to the files of a version regardless of how it is
+// stored: manifest and blobs, compressed archive or legacy directory
+type VersionReader struct {
+	Version string
+	Format  string
+	Files   []SnapshotFile
+
+	store    SnapshotStore
+	contents map[string][]byte
+}
+
+// OpenVersion opens a version for reading
+func OpenVersion(store SnapshotStore, version string) (*VersionReader, error) {
+	keys, err := store.List("versions/" + version + "/")
+	if err != nil {
+		return nil, err
+	}
+
+	format := ""
+	for _, key := range keys {
+		if f := versionFormat(version, key); f != "" && (format == "" || format == "legacy") {
+			format = f
+		}
+	}
+
+	reader := &VersionReader{Version: version, Format: format, store: store}
+	switch format {
+	case "":
+		return nil, &fs.PathError{Op: "open", Path: "version " + version, Err: fs.ErrNotExist}
+	case "manifest":
+		manifest, err := ReadManifest(store, version)
+		if err != nil {
+			return nil, err
+		}
+		reader.Files = manifest.Files
+	case "legacy":
+		for _, key := range keys {
+			if strings.HasPrefix(key, legacyPrefix(version)) {
+				reader.Files = append(reader.Files, SnapshotFile{Path: strings.TrimPrefix(key, legacyPrefix(version)), Mode: 0644})
+			}
+		}
+	default:
+		r, err := store.Get(archiveKey(version, format))
+		if err != nil {
+			return nil, err
+		}
+		defer r.Close()
+
+		reader.Format = format + " archive"
+		reader.Files, reader.contents, err = readArchive(r, format)
+		if err != nil {
+			return nil, fmt.Errorf("%s archive: %w", version, err)
+		}
+	}
+	return reader, nil
+}
+
+// Read returns the content of one of the version's files
+func (r *VersionReader) Read(file SnapshotFile) ([]byte, error) {
+	if r.contents != nil {
+		data, ok := r.contents[file.Path]
+		if !ok {
+			return nil, &fs.PathError{Op: "read", Path: file.Path, Err: fs.ErrNotExist}
+		}
+		return data, nil
+	}
+	if file.SHA256 != "" {
+		return GetObject(r.store, file.SHA256)
+	}
+
+	rc, err := r.store.Get(legacyPrefix(r.Version) + file.Path)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return io.ReadAll(rc)
+}
+
+// ReadFile returns the content of the file at path (slash-separated)
+func (r *VersionReader) ReadFile(path string) ([]byte, error) {
+	for _, f := range r.Files {
+		if f.Path == path {
+			return r.Read(f)
+		}
+	}
+	return nil, &fs.PathError{Op: "read", Path: r.Version + "/" + path, Err: fs.ErrNotExist}
+}
+
+// MaterializeVersion writes the configuration files of a version into dst
+func MaterializeVersion(store SnapshotStore, version, dst string) error {
+	_, err := MaterializeFiles(store, version, dst, nil)
+	return err
+}
+
+// MaterializeFiles writes the files of a version for which skip returns false
+// into dst and returns their paths
+func MaterializeFiles(store SnapshotStore, version, dst string, skip func(path string) bool) ([]string, error) {
+	reader, err := OpenVersion(store, version)
+	if err != nil {
+		return nil, err
+	}
+
+	var written []string
+	for _, f := range reader.Files {
+		if clean := path.Clean(f.Path); clean != f.Path || strings.HasPrefix(clean, "../") || path.IsAbs(clean) {
+			return written, fmt.Errorf("invalid path %q in version %s", f.Path, version)
+		}
+		if skip != nil && skip(f.Path) {
+			continue
+		}
+
+		data, err := reader.Read(f)
+		if err != nil {
+			return written, fmt.Errorf("%s: %w", f.Path, err)
+		}
+
+		target := filepath.Join(dst, filepath.FromSlash(f.Path))
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return written, err
+		}
+		if err := WriteFileAtomic(target, data, f.Mode|0200); err != nil {
+			return written, err
+		}
+		written = append(written, f.Path)
+	}
+	return written, nil
+}
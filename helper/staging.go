@@ -0,0 +1,156 @@
+package helper
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// stagingPrefix is where a version's files are written until the version is
+// complete and moved to versions/
+const stagingPrefix = "staging/"
+
+// dryRunPrefix names the scratch directories of dry runs in .cloudtm. The
+// PID of the process running the dry run follows, so directories left by a
+// killed process can be told apart from those of a running one.
+const dryRunPrefix = "dry-run-"
+
+// stagingStore redirects the keys of one version from versions/<version>/
+// to staging/<version>/; everything else (objects, metadata) is unchanged
+type stagingStore struct {
+	SnapshotStore
+	version string
+}
+
+// StagingStore returns a store that writes the files of version into
+// staging/<version>/ of store. CommitStagedVersion moves them into place.
+func StagingStore(store SnapshotStore, version string) SnapshotStore {
+	return &stagingStore{SnapshotStore: store, version: version}
+}
+
+func (s *stagingStore) stage(key string) string {
+	if rest, ok := strings.CutPrefix(key, "versions/"+s.version+"/"); ok {
+		return stagingPrefix + s.version + "/" + rest
+	}
+	return key
+}
+
+func (s *stagingStore) unstage(key string) string {
+	if rest, ok := strings.CutPrefix(key, stagingPrefix+s.version+"/"); ok {
+		return "versions/" + s.version + "/" + rest
+	}
+	return key
+}
+
+func (s *stagingStore) Put(key string, r io.Reader) error {
+	return s.SnapshotStore.Put(s.stage(key), r)
+}
+
+func (s *stagingStore) Get(key string) (io.ReadCloser, error) {
+	return s.SnapshotStore.Get(s.stage(key))
+}
+
+func (s *stagingStore) Delete(key string) error {
+	return s.SnapshotStore.Delete(s.stage(key))
+}
+
+func (s *stagingStore) List(prefix string) ([]string, error) {
+	keys, err := s.SnapshotStore.List(s.stage(prefix))
+	if err != nil {
+		return nil, err
+	}
+	for i, key := range keys {
+		keys[i] = s.unstage(key)
+	}
+	return keys, nil
+}
+
+// CommitStagedVersion moves a complete version from staging/ to versions/
+// of the local .cloudtm directory with a single rename
+func CommitStagedVersion(cloudtmDir, version string) error {
+	src := filepath.Join(cloudtmDir, filepath.FromSlash(stagingPrefix), version)
+	dst := filepath.Join(cloudtmDir, "versions", version)
+	if _, err := os.Stat(dst); err == nil {
+		return fmt.Errorf("version %s already exists", version)
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	if err := os.Rename(src, dst); err != nil {
+		return err
+	}
+	syncDir(filepath.Dir(dst))
+
+	// Leave no empty staging directory behind; it fails while others exist
+	os.Remove(filepath.Dir(src))
+	return nil
+}
+
+// CreateDryRunDir creates a scratch directory for a dry run in cloudtmDir
+func CreateDryRunDir(cloudtmDir string) (string, error) {
+	return os.MkdirTemp(cloudtmDir, fmt.Sprintf("%s%d-", dryRunPrefix, os.Getpid()))
+}
+
+// abandonedDryRun reports whether name is the scratch directory of a dry run
+// whose process no longer runs
+func abandonedDryRun(name string) bool {
+	rest, ok := strings.CutPrefix(name, dryRunPrefix)
+	if !ok {
+		return false
+	}
+	pid, _, _ := strings.Cut(rest, "-")
+	n, err := strconv.Atoi(pid)
+	return err != nil || n <= 0 || !processRunning(n)
+}
+
+// CleanStaging removes what an interrupted run left behind: incomplete
+// versions in staging/, scratch directories of dry runs that were killed
+// (they hold a copy of the live state) and temporary files of atomic writes.
+// The active rollback directory and the scratch directories of running dry
+// runs are not touched. It returns the incomplete versions.
+func CleanStaging(cloudtmDir string) ([]string, error) {
+	stagingDir := filepath.Join(cloudtmDir, filepath.FromSlash(stagingPrefix))
+	entries, err := os.ReadDir(stagingDir)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	var incomplete []string
+	for _, e := range entries {
+		incomplete = append(incomplete, e.Name())
+	}
+	if err := os.RemoveAll(stagingDir); err != nil {
+		return incomplete, err
+	}
+
+	err = filepath.WalkDir(cloudtmDir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if p == cloudtmDir || filepath.Dir(p) != cloudtmDir {
+				return nil
+			}
+			if d.Name() == "rollback" {
+				return filepath.SkipDir
+			}
+			if strings.HasPrefix(d.Name(), dryRunPrefix) {
+				if abandonedDryRun(d.Name()) {
+					if err := os.RemoveAll(p); err != nil {
+						return err
+					}
+				}
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if IsTempFile(d.Name()) {
+			return os.Remove(p)
+		}
+		return nil
+	})
+	return incomplete, err
+}
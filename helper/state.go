@@ -1,37 +1,351 @@
 package helper
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 )
 
-// TerraformState represents the structure of terraform.tfstate
+// StateFileName is the local state file Terraform writes in a project
+const StateFileName = "terraform.tfstate"
+
+// StateFormatVersion is the tfstate format version understood by cloudtm
+const StateFormatVersion = 4
+
+// TerraformState is a Terraform state file (format version 4, written by
+// Terraform 0.12 and later and by OpenTofu)
 type TerraformState struct {
-	Resources []interface{} `json:"resources"`
+	Version          int                    `json:"version"`
+	TerraformVersion string                 `json:"terraform_version"`
+	Serial           uint64                 `json:"serial"`
+	Lineage          string                 `json:"lineage"`
+	Outputs          map[string]StateOutput `json:"outputs"`
+	Resources        []StateResource        `json:"resources"`
+	CheckResults     json.RawMessage        `json:"check_results,omitempty"`
+}
+
+// StateOutput is a root module output value
+type StateOutput struct {
+	Value     interface{}     `json:"value"`
+	Type      json.RawMessage `json:"type"`
+	Sensitive bool            `json:"sensitive,omitempty"`
+}
+
+// StateResource is a resource block (managed or data) and its instances
+type StateResource struct {
+	Module    string          `json:"module,omitempty"`
+	Mode      string          `json:"mode"`
+	Type      string          `json:"type"`
+	Name      string          `json:"name"`
+	Each      string          `json:"each,omitempty"`
+	Provider  string          `json:"provider"`
+	Instances []StateInstance `json:"instances"`
+}
+
+// StateInstance is a single instance of a resource (one per count index or
+// for_each key, plus deposed objects awaiting destruction)
+type StateInstance struct {
+	IndexKey            interface{}            `json:"index_key,omitempty"`
+	Status              string                 `json:"status,omitempty"`
+	Deposed             string                 `json:"deposed,omitempty"`
+	SchemaVersion       int                    `json:"schema_version"`
+	Attributes          map[string]interface{} `json:"attributes,omitempty"`
+	AttributesFlat      map[string]string      `json:"attributes_flat,omitempty"`
+	SensitiveAttributes []json.RawMessage      `json:"sensitive_attributes,omitempty"`
+	Private             string                 `json:"private,omitempty"`
+	Dependencies        []string               `json:"dependencies,omitempty"`
+	CreateBeforeDestroy bool                   `json:"create_before_destroy,omitempty"`
+}
+
+// ResourceInstance pairs an instance with its resource and full address
+type ResourceInstance struct {
+	Address  string
+	Resource *StateResource
+	Instance *StateInstance
+}
+
+// ParseState decodes a state file. Numbers are kept as json.Number so large
+// integers survive unchanged. Empty data is an empty state.
+func ParseState(data []byte) (*TerraformState, error) {
+	if len(bytes.TrimSpace(data)) == 0 {
+		return &TerraformState{Version: StateFormatVersion}, nil
+	}
+
+	var state TerraformState
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&state); err != nil {
+		return nil, fmt.Errorf("parsing state: %w", err)
+	}
+	if state.Version != StateFormatVersion {
+		return nil, &UnsupportedStateError{Version: state.Version}
+	}
+	return &state, nil
+}
+
+// UnsupportedStateError is returned for a state file in a format version
+// other than StateFormatVersion
+type UnsupportedStateError struct {
+	Version int
+}
+
+func (e *UnsupportedStateError) Error() string {
+	return fmt.Sprintf("unsupported state format version %d (cloudtm reads version %d, written by Terraform 0.12 and later)",
+		e.Version, StateFormatVersion)
+}
+
+// ReadState reads and decodes a state file
+func ReadState(path string) (*TerraformState, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	state, err := ParseState(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return state, nil
+}
+
+// IsEmpty reports whether the state tracks no resources
+func (s *TerraformState) IsEmpty() bool {
+	return len(s.Resources) == 0
+}
+
+// Address returns the resource address, e.g. "module.net.aws_vpc.main" or
+// "data.aws_ami.ubuntu"
+func (r *StateResource) Address() string {
+	addr := r.Type + "." + r.Name
+	if r.Mode == "data" {
+		addr = "data." + addr
+	}
+	if r.Module != "" {
+		addr = r.Module + "." + addr
+	}
+	return addr
+}
+
+// Address returns the instance address within its resource, e.g.
+// `aws_instance.web[0]` or `aws_instance.web["blue"]`. Deposed objects are
+// suffixed with their deposed key.
+func (i *StateInstance) Address(resource *StateResource) string {
+	addr := resource.Address()
+	switch key := i.IndexKey.(type) {
+	case string:
+		addr += fmt.Sprintf("[%q]", key)
+	case json.Number:
+		addr += "[" + key.String() + "]"
+	case float64:
+		addr += fmt.Sprintf("[%d]", int(key))
+	}
+	if i.Deposed != "" {
+		addr += " (deposed " + i.Deposed + ")"
+	}
+	return addr
+}
+
+// Instances returns every resource instance in the state, in state order
+func (s *TerraformState) Instances() []ResourceInstance {
+	var instances []ResourceInstance
+	for r := range s.Resources {
+		resource := &s.Resources[r]
+		for i := range resource.Instances {
+			instance := &resource.Instances[i]
+			instances = append(instances, ResourceInstance{
+				Address:  instance.Address(resource),
+				Resource: resource,
+				Instance: instance,
+			})
+		}
+	}
+	return instances
 }
 
-// IsStateEmpty checks if terraform.tfstate has empty resources array
+// ManagedInstances returns the instances of managed resources (not data sources)
+func (s *TerraformState) ManagedInstances() []ResourceInstance {
+	var managed []ResourceInstance
+	for _, inst := range s.Instances() {
+		if inst.Resource.Mode == "managed" {
+			managed = append(managed, inst)
+		}
+	}
+	return managed
+}
+
+// Instance returns the instance with the given address, or nil
+func (s *TerraformState) Instance(address string) *ResourceInstance {
+	for _, inst := range s.Instances() {
+		if inst.Address == address {
+			return &inst
+		}
+	}
+	return nil
+}
+
+// ResourcesOfType returns the instances of resources with the given type
+func (s *TerraformState) ResourcesOfType(resourceType string) []ResourceInstance {
+	var matches []ResourceInstance
+	for _, inst := range s.Instances() {
+		if inst.Resource.Type == resourceType {
+			matches = append(matches, inst)
+		}
+	}
+	return matches
+}
+
+// Providers returns the distinct provider configurations used by the state, sorted
+func (s *TerraformState) Providers() []string {
+	seen := make(map[string]bool)
+	var providers []string
+	for _, r := range s.Resources {
+		if r.Provider != "" && !seen[r.Provider] {
+			seen[r.Provider] = true
+			providers = append(providers, r.Provider)
+		}
+	}
+	sort.Strings(providers)
+	return providers
+}
+
+// FlatAttributes maps every leaf attribute to its path in Terraform's
+// notation (e.g. "tags.Name", "ingress[0].from_port")
+func (i *StateInstance) FlatAttributes() map[string]interface{} {
+	flat := make(map[string]interface{})
+	for key, value := range i.Attributes {
+		flattenAttributes(key, value, flat)
+	}
+	return flat
+}
+
+// flattenAttributes maps nested attribute values to paths
+func flattenAttributes(prefix string, value interface{}, out map[string]interface{}) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if len(v) == 0 {
+			out[prefix] = v
+		}
+		for key, child := range v {
+			flattenAttributes(prefix+"."+key, child, out)
+		}
+	case []interface{}:
+		if len(v) == 0 {
+			out[prefix] = v
+		}
+		for i, child := range v {
+			flattenAttributes(fmt.Sprintf("%s[%d]", prefix, i), child, out)
+		}
+	default:
+		out[prefix] = v
+	}
+}
+
+// SensitivePaths returns the attribute paths Terraform marked as sensitive,
+// in the notation used by FlatAttributes
+func (i *StateInstance) SensitivePaths() []string {
+	var paths []string
+	for _, raw := range i.SensitiveAttributes {
+		if path := sensitivePath(raw); path != "" {
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
+
+// IsSensitive reports whether the attribute path or one of its parents is sensitive
+func (i *StateInstance) IsSensitive(path string) bool {
+	for _, p := range i.SensitivePaths() {
+		if path == p || strings.HasPrefix(path, p+".") || strings.HasPrefix(path, p+"[") {
+			return true
+		}
+	}
+	return false
+}
+
+// sensitivePath converts a sensitive_attributes entry (a list of path steps)
+// into attribute path notation
+func sensitivePath(raw json.RawMessage) string {
+	var steps []struct {
+		Type  string          `json:"type"`
+		Value json.RawMessage `json:"value"`
+	}
+	if err := json.Unmarshal(raw, &steps); err != nil {
+		return ""
+	}
+
+	var path strings.Builder
+	for _, step := range steps {
+		switch step.Type {
+		case "get_attr":
+			var name string
+			json.Unmarshal(step.Value, &name)
+			if path.Len() > 0 {
+				path.WriteByte('.')
+			}
+			path.WriteString(name)
+		case "index":
+			var key struct {
+				Value interface{} `json:"value"`
+			}
+			json.Unmarshal(step.Value, &key)
+			switch k := key.Value.(type) {
+			case float64:
+				fmt.Fprintf(&path, "[%d]", int(k))
+			case string:
+				// Map keys are flattened like attributes
+				path.WriteString("." + k)
+			}
+		}
+	}
+	return path.String()
+}
+
+// IsStateEmpty checks if terraform.tfstate has empty resources array. State
+// files in other format versions are only checked for resources.
 func IsStateEmpty(workingDir string) (bool, error) {
-	stateFile := filepath.Join(workingDir, "terraform.tfstate")
+	stateFile := filepath.Join(workingDir, StateFileName)
 
 	// Check if state file exists
 	if _, err := os.Stat(stateFile); os.IsNotExist(err) {
 		return false, err
 	}
 
-	// Read state file
 	data, err := os.ReadFile(stateFile)
 	if err != nil {
 		return false, err
 	}
+	state, err := ParseState(data)
+	var unsupported *UnsupportedStateError
+	if errors.As(err, &unsupported) {
+		return hasNoResources(data)
+	}
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", stateFile, err)
+	}
+	return state.IsEmpty(), nil
+}
 
-	// Parse JSON
-	var state TerraformState
+// hasNoResources reports whether a state file of any format version tracks
+// no resources: neither top-level resources nor, in format version 3 and
+// earlier, resources of a module
+func hasNoResources(data []byte) (bool, error) {
+	var state struct {
+		Resources []json.RawMessage `json:"resources"`
+		Modules   []struct {
+			Resources map[string]json.RawMessage `json:"resources"`
+		} `json:"modules"`
+	}
 	if err := json.Unmarshal(data, &state); err != nil {
-		return false, err
+		return false, fmt.Errorf("parsing state: %w", err)
+	}
+	for _, module := range state.Modules {
+		if len(module.Resources) > 0 {
+			return false, nil
+		}
 	}
-
-	// Check if resources array is empty
 	return len(state.Resources) == 0, nil
 }
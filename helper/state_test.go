@@ -0,0 +1,296 @@
+package helper
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// testState is a format version 4 state with a counted resource, a for_each
+// resource in a module, a data source and a deposed object
+const testState = `{
+  "version": 4,
+  "terraform_version": "1.9.0",
+  "serial": 7,
+  "lineage": "3f1c",
+  "outputs": {
+    "ip": {"value": "10.0.0.1", "type": "string"},
+    "password": {"value": "hunter2", "type": "string", "sensitive": true}
+  },
+  "resources": [
+    {"mode": "managed", "type": "aws_instance", "name": "web", "each": "list",
+     "provider": "provider[\"registry.terraform.io/hashicorp/aws\"]",
+     "instances": [
+       {"index_key": 0, "schema_version": 1, "attributes": {"id": "i-0", "size": 9007199254740993}},
+       {"index_key": 1, "schema_version": 1, "attributes": {"id": "i-1"}},
+       {"index_key": 1, "deposed": "00aa", "schema_version": 1, "attributes": {"id": "i-old"}}
+     ]},
+    {"module": "module.net", "mode": "managed", "type": "aws_subnet", "name": "private", "each": "map",
+     "provider": "provider[\"registry.terraform.io/hashicorp/aws\"]",
+     "instances": [{"index_key": "blue", "schema_version": 0, "attributes": {"id": "subnet-1"}}]},
+    {"mode": "data", "type": "aws_ami", "name": "ubuntu",
+     "provider": "provider[\"registry.terraform.io/hashicorp/aws\"]",
+     "instances": [{"schema_version": 0, "attributes": {"id": "ami-1"}}]}
+  ]
+}`
+
+func mustParseState(t *testing.T, data string) *TerraformState {
+	t.Helper()
+	state, err := ParseState([]byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return state
+}
+
+func TestParseState(t *testing.T) {
+	tests := []struct {
+		name        string
+		data        string
+		wantErr     string
+		unsupported bool
+		resources   int
+	}{
+		{"format version 4", testState, "", false, 3},
+		{"empty file", "", "", false, 0},
+		{"whitespace", " \n", "", false, 0},
+		{"no resources", `{"version": 4, "lineage": "x", "resources": []}`, "", false, 0},
+		{"format version 3", `{"version": 3, "modules": []}`, "unsupported state format version 3", true, 0},
+		{"newer format version", `{"version": 5}`, "unsupported state format version 5", true, 0},
+		{"invalid JSON", `{"version": 4,`, "parsing state", false, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state, err := ParseState([]byte(tt.data))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("got error %v, want one containing %q", err, tt.wantErr)
+				}
+				var unsupported *UnsupportedStateError
+				if errors.As(err, &unsupported) != tt.unsupported {
+					t.Errorf("UnsupportedStateError = %v, want %v", !tt.unsupported, tt.unsupported)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(state.Resources) != tt.resources || state.IsEmpty() != (tt.resources == 0) {
+				t.Errorf("%d resources (empty %v), want %d", len(state.Resources), state.IsEmpty(), tt.resources)
+			}
+		})
+	}
+}
+
+func TestParseStateKeepsLargeNumbers(t *testing.T) {
+	state := mustParseState(t, testState)
+	size := state.Resources[0].Instances[0].Attributes["size"]
+	if n, ok := size.(json.Number); !ok || n.String() != "9007199254740993" {
+		t.Errorf("size = %#v, want json.Number 9007199254740993", size)
+	}
+	if state.Serial != 7 || state.Lineage != "3f1c" {
+		t.Errorf("serial %d, lineage %q", state.Serial, state.Lineage)
+	}
+}
+
+func TestInstances(t *testing.T) {
+	state := mustParseState(t, testState)
+
+	var addresses []string
+	for _, inst := range state.Instances() {
+		addresses = append(addresses, inst.Address)
+	}
+	want := []string{
+		"aws_instance.web[0]",
+		"aws_instance.web[1]",
+		"aws_instance.web[1] (deposed 00aa)",
+		`module.net.aws_subnet.private["blue"]`,
+		"data.aws_ami.ubuntu",
+	}
+	if !reflect.DeepEqual(addresses, want) {
+		t.Errorf("Instances addresses = %q, want %q", addresses, want)
+	}
+
+	if n := len(state.ManagedInstances()); n != 4 {
+		t.Errorf("ManagedInstances returned %d instances, want 4", n)
+	}
+	if n := len(state.ResourcesOfType("aws_instance")); n != 3 {
+		t.Errorf("ResourcesOfType(aws_instance) returned %d instances, want 3", n)
+	}
+	inst := state.Instance(`module.net.aws_subnet.private["blue"]`)
+	if inst == nil || inst.Instance.Attributes["id"] != "subnet-1" {
+		t.Errorf("Instance by address = %+v", inst)
+	}
+	if state.Instance("aws_instance.web[2]") != nil {
+		t.Error("Instance found an address that does not exist")
+	}
+	if got := state.Providers(); len(got) != 1 || !strings.Contains(got[0], "hashicorp/aws") {
+		t.Errorf("Providers = %v", got)
+	}
+}
+
+func TestInstanceAddress(t *testing.T) {
+	resource := &StateResource{Mode: "managed", Type: "aws_instance", Name: "web"}
+	tests := []struct {
+		name     string
+		resource *StateResource
+		instance StateInstance
+		want     string
+	}{
+		{"single", resource, StateInstance{}, "aws_instance.web"},
+		{"count index", resource, StateInstance{IndexKey: json.Number("3")}, "aws_instance.web[3]"},
+		{"float index", resource, StateInstance{IndexKey: float64(2)}, "aws_instance.web[2]"},
+		{"for_each key", resource, StateInstance{IndexKey: "a b"}, `aws_instance.web["a b"]`},
+		{"deposed", resource, StateInstance{Deposed: "ff01"}, "aws_instance.web (deposed ff01)"},
+		{"data source", &StateResource{Mode: "data", Type: "aws_ami", Name: "x"}, StateInstance{}, "data.aws_ami.x"},
+		{"module", &StateResource{Module: "module.a.module.b", Mode: "managed", Type: "t", Name: "n"}, StateInstance{}, "module.a.module.b.t.n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.instance.Address(tt.resource); got != tt.want {
+				t.Errorf("Address = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFlatAttributesAndSensitivePaths(t *testing.T) {
+	inst := &StateInstance{
+		Attributes: map[string]interface{}{
+			"tags":    map[string]interface{}{"Name": "web"},
+			"ingress": []interface{}{map[string]interface{}{"from_port": 22.0}},
+			"empty":   []interface{}{},
+		},
+		SensitiveAttributes: []json.RawMessage{
+			json.RawMessage(`[{"type":"get_attr","value":"ingress"},{"type":"index","value":{"value":0,"type":"number"}}]`),
+			json.RawMessage(`[{"type":"get_attr","value":"tags"},{"type":"index","value":{"value":"Secret","type":"string"}}]`),
+		},
+	}
+
+	want := map[string]interface{}{
+		"tags.Name":            "web",
+		"ingress[0].from_port": 22.0,
+		"empty":                []interface{}{},
+	}
+	if got := inst.FlatAttributes(); !reflect.DeepEqual(got, want) {
+		t.Errorf("FlatAttributes = %v, want %v", got, want)
+	}
+	if got := inst.SensitivePaths(); !reflect.DeepEqual(got, []string{"ingress[0]", "tags.Secret"}) {
+		t.Errorf("SensitivePaths = %q", got)
+	}
+
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{"ingress[0]", true},
+		{"ingress[0].from_port", true},
+		{"ingress[1]", false},
+		{"tags.Secret", true},
+		{"tags.Name", false},
+		{"tags.SecretKey", false},
+	}
+	for _, tt := range tests {
+		if got := inst.IsSensitive(tt.path); got != tt.want {
+			t.Errorf("IsSensitive(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestDiffStates(t *testing.T) {
+	base := `{"version": 4, "outputs": {%s}, "resources": [%s]}`
+	resource := func(name, attrs string) string {
+		return `{"mode": "managed", "type": "t", "name": "` + name + `", "instances": [{"attributes": ` + attrs + `}]}`
+	}
+	secret := `{"mode": "managed", "type": "t", "name": "db", "instances": [{"attributes": {"password": "%s"},
+		"sensitive_attributes": [[{"type": "get_attr", "value": "password"}]]}]}`
+	state := func(outputs string, resources ...string) string {
+		return strings.Replace(strings.Replace(base, "%s", outputs, 1), "%s", strings.Join(resources, ","), 1)
+	}
+
+	tests := []struct {
+		name      string
+		a, b      string
+		resources []ResourceDiff
+		outputs   []AttributeDiff
+	}{
+		{"identical", state("", resource("a", `{"id": "1"}`)), state("", resource("a", `{"id": "1"}`)), nil, nil},
+		{"empty to empty", "", "", nil, nil},
+		{"added", "", state("", resource("a", `{"id": "1"}`)),
+			[]ResourceDiff{{Address: "t.a", Action: "added"}}, nil},
+		{"removed", state("", resource("a", `{"id": "1"}`)), state(""),
+			[]ResourceDiff{{Address: "t.a", Action: "removed"}}, nil},
+		{"changed attribute", state("", resource("a", `{"id": "1", "size": 1}`)), state("", resource("a", `{"id": "1", "size": 2, "new": true}`)),
+			[]ResourceDiff{{Address: "t.a", Action: "changed", Attributes: []AttributeDiff{
+				{Path: "new", New: true, HasNew: true},
+				{Path: "size", Old: json.Number("1"), New: json.Number("2"), HasOld: true, HasNew: true},
+			}}}, nil},
+		{"sensitive attribute", state("", strings.Replace(secret, "%s", "old", 1)), state("", strings.Replace(secret, "%s", "new", 1)),
+			[]ResourceDiff{{Address: "t.db", Action: "changed", Attributes: []AttributeDiff{
+				{Path: "password", HasOld: true, HasNew: true, Sensitive: true},
+			}}}, nil},
+		{"outputs", state(`"ip": {"value": "a"}, "key": {"value": "x", "sensitive": true}`), state(`"ip": {"value": "b"}, "key": {"value": "y", "sensitive": true}`),
+			nil, []AttributeDiff{
+				{Path: "ip", Old: "a", New: "b", HasOld: true, HasNew: true},
+				{Path: "key", HasOld: true, HasNew: true, Sensitive: true},
+			}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diff, err := DiffStates([]byte(tt.a), []byte(tt.b))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(diff.Resources, tt.resources) {
+				t.Errorf("resources %+v, want %+v", diff.Resources, tt.resources)
+			}
+			if !reflect.DeepEqual(diff.Outputs, tt.outputs) {
+				t.Errorf("outputs %+v, want %+v", diff.Outputs, tt.outputs)
+			}
+			if diff.IsEmpty() != (tt.resources == nil && tt.outputs == nil) {
+				t.Errorf("IsEmpty = %v", diff.IsEmpty())
+			}
+		})
+	}
+
+	if _, err := DiffStates([]byte(`{"version": 3}`), nil); err == nil {
+		t.Error("DiffStates accepted an unsupported state format")
+	}
+}
+
+func TestIsStateEmpty(t *testing.T) {
+	tests := []struct {
+		name    string
+		state   string
+		want    bool
+		wantErr bool
+	}{
+		{"no resources", `{"version": 4, "resources": []}`, true, false},
+		{"resources", testState, false, false},
+		{"empty file", "", true, false},
+		{"format version 3 without resources", `{"version": 3, "modules": [{"path": ["root"], "resources": {}}]}`, true, false},
+		{"format version 3 with resources", `{"version": 3, "modules": [{"path": ["root"], "resources": {"aws_instance.web": {}}}]}`, false, false},
+		{"newer format version with resources", `{"version": 5, "resources": [{}]}`, false, false},
+		{"invalid JSON", `{`, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if err := os.WriteFile(filepath.Join(dir, StateFileName), []byte(tt.state), 0644); err != nil {
+				t.Fatal(err)
+			}
+			got, err := IsStateEmpty(dir)
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Errorf("IsStateEmpty = %v, %v; want %v, error %v", got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+
+	if _, err := IsStateEmpty(t.TempDir()); !os.IsNotExist(err) {
+		t.Errorf("missing state file: got %v, want a not-exist error", err)
+	}
+}
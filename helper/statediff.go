@@ -0,0 +1,140 @@
+package helper
+
+import (
+	"encoding/json"
+	"sort"
+)
+
+// StateDiff describes how one state differs from another
+type StateDiff struct {
+	Resources []ResourceDiff
+	Outputs   []AttributeDiff
+}
+
+// ResourceDiff describes how a resource instance differs between two states
+type ResourceDiff struct {
+	Address    string
+	Action     string // "added", "removed" or "changed"
+	Attributes []AttributeDiff
+}
+
+// AttributeDiff describes a changed attribute of a resource instance or a
+// changed output. Paths use Terraform's notation (e.g. "tags.Name",
+// "ingress[0].from_port"). Sensitive values are never included.
+type AttributeDiff struct {
+	Path      string
+	Old       interface{}
+	New       interface{}
+	HasOld    bool
+	HasNew    bool
+	Sensitive bool
+}
+
+// IsEmpty reports whether the states have the same resources and outputs
+func (d *StateDiff) IsEmpty() bool {
+	return len(d.Resources) == 0 && len(d.Outputs) == 0
+}
+
+// DiffStates parses two tfstate files and compares them. Missing or empty
+// data is treated as an empty state.
+func DiffStates(a, b []byte) (*StateDiff, error) {
+	aState, err := ParseState(a)
+	if err != nil {
+		return nil, err
+	}
+	bState, err := ParseState(b)
+	if err != nil {
+		return nil, err
+	}
+	return CompareStates(aState, bState), nil
+}
+
+// CompareStates compares resource instances by address and outputs by name
+func CompareStates(a, b *TerraformState) *StateDiff {
+	aInstances := instancesByAddress(a)
+	bInstances := instancesByAddress(b)
+
+	diff := &StateDiff{}
+	for _, addr := range sortedUnion(aInstances, bInstances) {
+		aInst, inA := aInstances[addr]
+		bInst, inB := bInstances[addr]
+		switch {
+		case !inA:
+			diff.Resources = append(diff.Resources, ResourceDiff{Address: addr, Action: "added"})
+		case !inB:
+			diff.Resources = append(diff.Resources, ResourceDiff{Address: addr, Action: "removed"})
+		default:
+			if attrs := diffAttributes(aInst, bInst); len(attrs) > 0 {
+				diff.Resources = append(diff.Resources, ResourceDiff{Address: addr, Action: "changed", Attributes: attrs})
+			}
+		}
+	}
+
+	for _, name := range sortedUnion(a.Outputs, b.Outputs) {
+		aOut, inA := a.Outputs[name]
+		bOut, inB := b.Outputs[name]
+		if inA && inB && jsonEqual(aOut.Value, bOut.Value) {
+			continue
+		}
+		d := AttributeDiff{Path: name, HasOld: inA, HasNew: inB}
+		if aOut.Sensitive || bOut.Sensitive {
+			d.Sensitive = true
+		} else {
+			d.Old, d.New = aOut.Value, bOut.Value
+		}
+		diff.Outputs = append(diff.Outputs, d)
+	}
+	return diff
+}
+
+func instancesByAddress(s *TerraformState) map[string]*StateInstance {
+	instances := make(map[string]*StateInstance)
+	for _, inst := range s.Instances() {
+		instances[inst.Address] = inst.Instance
+	}
+	return instances
+}
+
+// sortedUnion returns the keys of both maps, sorted
+func sortedUnion[V any](a, b map[string]V) []string {
+	keys := make(map[string]bool)
+	for k := range a {
+		keys[k] = true
+	}
+	for k := range b {
+		keys[k] = true
+	}
+	sorted := make([]string, 0, len(keys))
+	for k := range keys {
+		sorted = append(sorted, k)
+	}
+	sort.Strings(sorted)
+	return sorted
+}
+
+func diffAttributes(a, b *StateInstance) []AttributeDiff {
+	aAttrs, bAttrs := a.FlatAttributes(), b.FlatAttributes()
+
+	var diffs []AttributeDiff
+	for _, p := range sortedUnion(aAttrs, bAttrs) {
+		aVal, inA := aAttrs[p]
+		bVal, inB := bAttrs[p]
+		if inA && inB && jsonEqual(aVal, bVal) {
+			continue
+		}
+		d := AttributeDiff{Path: p, HasOld: inA, HasNew: inB}
+		if a.IsSensitive(p) || b.IsSensitive(p) {
+			d.Sensitive = true
+		} else {
+			d.Old, d.New = aVal, bVal
+		}
+		diffs = append(diffs, d)
+	}
+	return diffs
+}
+
+func jsonEqual(a, b interface{}) bool {
+	aJSON, _ := json.Marshal(a)
+	bJSON, _ := json.Marshal(b)
+	return string(aJSON) == string(bJSON)
+}
@@ -0,0 +1,147 @@
+package helper
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// SnapshotStore stores cloudtm objects (snapshot files and metadata) under
+// slash-separated keys such as "versions/v1/tf_configs/main.tf" or "meta/v1.json"
+type SnapshotStore interface {
+	// Put writes the object at key, replacing any existing object
+	Put(key string, r io.Reader) error
+	// Get opens the object at key. Missing objects return an error matching fs.ErrNotExist.
+	Get(key string) (io.ReadCloser, error)
+	// List returns all keys starting with prefix, sorted
+	List(prefix string) ([]string, error)
+	// Delete removes the object at key
+	Delete(key string) error
+	// String describes the store location for messages
+	String() string
+}
+
+// OpenStore opens a store from a location: a local path or file:// URL,
+// or an s3://bucket/prefix URL for S3-compatible storage
+func OpenStore(location string) (SnapshotStore, error) {
+	switch {
+	case strings.HasPrefix(location, "s3://"):
+		return NewS3StoreFromURL(location)
+	case strings.HasPrefix(location, "file://"):
+		return &FileStore{Root: strings.TrimPrefix(location, "file://")}, nil
+	case strings.Contains(location, "://"):
+		return nil, fmt.Errorf("unsupported store location %q (use a path, file:// or s3://)", location)
+	case location == "":
+		return nil, fmt.Errorf("empty store location")
+	default:
+		return &FileStore{Root: location}, nil
+	}
+}
+
+// LocalStore returns the store backed by the project's .cloudtm directory,
+// without encryption
+func LocalStore(cloudtmDir string) SnapshotStore {
+	return &FileStore{Root: cloudtmDir}
+}
+
+// FileStore stores objects as files below a root directory
+type FileStore struct {
+	Root string
+}
+
+func (s *FileStore) String() string {
+	return s.Root
+}
+
+func (s *FileStore) path(key string) (string, error) {
+	clean := path.Clean("/" + key)
+	if clean == "/" || clean != "/"+key {
+		return "", fmt.Errorf("invalid object key %q", key)
+	}
+	return filepath.Join(s.Root, filepath.FromSlash(key)), nil
+}
+
+// Put atomically writes the object as a file readable only by the owner,
+// creating parent directories as needed. Snapshot content includes state with
+// secrets.
+func (s *FileStore) Put(key string, r io.Reader) error {
+	dst, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+
+	return writeAtomic(dst, 0600, func(w io.Writer) error {
+		_, err := io.Copy(w, r)
+		return err
+	})
+}
+
+// Get opens the object's file
+func (s *FileStore) Get(key string) (io.ReadCloser, error) {
+	src, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(src)
+}
+
+// List walks the directory containing prefix and returns matching keys
+func (s *FileStore) List(prefix string) ([]string, error) {
+	dir := s.Root
+	if i := strings.LastIndex(prefix, "/"); i >= 0 {
+		dir = filepath.Join(s.Root, filepath.FromSlash(prefix[:i]))
+	}
+
+	var keys []string
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if d.IsDir() || IsTempFile(d.Name()) {
+			return nil
+		}
+		rel, err := filepath.Rel(s.Root, p)
+		if err != nil {
+			return err
+		}
+		key := filepath.ToSlash(rel)
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// Delete removes the object's file and any directories left empty by it
+func (s *FileStore) Delete(key string) error {
+	dst, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(dst); err != nil {
+		return err
+	}
+	for dir := filepath.Dir(dst); dir != filepath.Clean(s.Root); dir = filepath.Dir(dir) {
+		if os.Remove(dir) != nil {
+			break
+		}
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package helper
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"strings"
+)
+
+// StoreVersions lists the versions that have metadata in the store
+func StoreVersions(store SnapshotStore) ([]string, error) {
+	keys, err := store.List("meta/")
+	if err != nil {
+		return nil, err
+	}
+
+	var versions []string
+	for _, key := range keys {
+		name := path.Base(key)
+		if path.Dir(key) == "meta" && strings.HasSuffix(name, ".json") {
+			versions = append(versions, strings.TrimSuffix(name, ".json"))
+		}
+	}
+	return versions, nil
+}
+
+// ReadStoreMetadata reads a version's metadata from the store
+func ReadStoreMetadata(store SnapshotStore, version string) (*Metadata, error) {
+	r, err := store.Get("meta/" + version + ".json")
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return DecodeMetadata(data)
+}
+
+// CopyVersion copies all objects of a version from src to dst, including the
+// blobs its manifest references that dst does not have yet. The metadata is
+// copied last so an interrupted copy never looks like a complete version.
+func CopyVersion(src, dst SnapshotStore, version string) error {
+	manifest, err := ReadManifest(src, version)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	if manifest != nil {
+		existing, err := ListObjects(dst)
+		if err != nil {
+			return err
+		}
+		for _, f := range manifest.Files {
+			if existing[f.SHA256] {
+				continue
+			}
+			data, err := GetObject(src, f.SHA256)
+			if err != nil {
+				return fmt.Errorf("%s: %w", f.Path, err)
+			}
+			if _, _, err := PutObject(dst, data, existing); err != nil {
+				return err
+			}
+		}
+	}
+
+	keys, err := src.List("versions/" + version + "/")
+	if err != nil {
+		return err
+	}
+
+	for _, key := range append(keys, "meta/"+version+".json") {
+		if err := copyObject(src, dst, key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func copyObject(src, dst SnapshotStore, key string) error {
+	r, err := src.Get(key)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	return dst.Put(key, r)
+}
+
+// SnapshotVersions lists the versions that have objects below versions/ in the store
+func SnapshotVersions(store SnapshotStore) ([]string, error) {
+	keys, err := store.List("versions/")
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	var versions []string
+	for _, key := range keys {
+		parts := strings.SplitN(key, "/", 3)
+		if len(parts) < 3 || seen[parts[1]] {
+			continue
+		}
+		seen[parts[1]] = true
+		versions = append(versions, parts[1])
+	}
+	return versions, nil
+}
@@ -0,0 +1,124 @@
+package helper
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+)
+
+// Tag is a human-readable name for a version. Pinned tags protect the
+// version from 'cloudtm prune'.
+type Tag struct {
+	Name   string `json:"name"`
+	Pinned bool   `json:"pinned,omitempty"`
+}
+
+var (
+	versionNamePattern = regexp.MustCompile(`^v[0-9]+$`)
+	tagNamePattern     = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+)
+
+// IsVersionName reports whether ref is a version name such as "v7"
+func IsVersionName(ref string) bool {
+	return versionNamePattern.MatchString(ref)
+}
+
+// ValidateTagName checks that a tag name can be told apart from version names
+func ValidateTagName(name string) error {
+	if IsVersionName(name) {
+		return fmt.Errorf("tag %q looks like a version name", name)
+	}
+	if !tagNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid tag %q (use letters, digits, '.', '_' and '-')", name)
+	}
+	return nil
+}
+
+// HasTag reports whether the version carries the tag
+func (m *Metadata) HasTag(name string) bool {
+	for _, t := range m.Tags {
+		if t.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// SetTag adds the tag to the version or updates whether it is pinned
+func (m *Metadata) SetTag(name string, pinned bool) {
+	for i, t := range m.Tags {
+		if t.Name == name {
+			m.Tags[i].Pinned = pinned
+			return
+		}
+	}
+	m.Tags = append(m.Tags, Tag{Name: name, Pinned: pinned})
+	sort.Slice(m.Tags, func(i, j int) bool { return m.Tags[i].Name < m.Tags[j].Name })
+}
+
+// RemoveTag removes the tag from the version and reports whether it was present
+func (m *Metadata) RemoveTag(name string) bool {
+	for i, t := range m.Tags {
+		if t.Name == name {
+			m.Tags = append(m.Tags[:i], m.Tags[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// IsPinned reports whether the version is pinned directly or by a pinned tag
+func (m *Metadata) IsPinned() bool {
+	if m.Pinned {
+		return true
+	}
+	for _, t := range m.Tags {
+		if t.Pinned {
+			return true
+		}
+	}
+	return false
+}
+
+// FindTag returns the metadata of the version carrying the tag, or nil
+func FindTag(metas []*Metadata, name string) *Metadata {
+	for _, meta := range metas {
+		if meta.HasTag(name) {
+			return meta
+		}
+	}
+	return nil
+}
+
+// UnknownRefError is returned by ResolveVersion for a tag no version carries
+type UnknownRefError struct {
+	Ref string
+}
+
+func (e *UnknownRefError) Error() string {
+	return fmt.Sprintf("unknown version or tag '%s'", e.Ref)
+}
+
+// ResolveVersion turns a version name or tag into a version name using the
+// metadata in the store. Version names are returned unchanged without
+// checking that they exist.
+func ResolveVersion(store SnapshotStore, ref string) (string, error) {
+	if IsVersionName(ref) {
+		return ref, nil
+	}
+
+	versions, err := StoreVersions(store)
+	if err != nil {
+		return "", err
+	}
+	for _, v := range versions {
+		meta, err := ReadStoreMetadata(store, v)
+		if err != nil {
+			continue
+		}
+		if meta.HasTag(ref) {
+			return meta.Version, nil
+		}
+	}
+	return "", &UnknownRefError{Ref: ref}
+}
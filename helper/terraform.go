@@ -0,0 +1,48 @@
+package helper
+
+import (
+	"bytes"
+	"io"
+	"os"
+)
+
+// RunTerraformJSON runs terraform with the given arguments in dir, expecting
+// the -json event stream on stdout. Events are rendered to out as they arrive.
+// The parsed result is returned even when terraform exits with an error;
+// anything terraform wrote to stderr is passed through and kept in it.
+func RunTerraformJSON(dir string, args []string, out io.Writer) (*RunResult, error) {
+	var stderr bytes.Buffer
+	tfCmd := TerraformCommand(dir, args...)
+	tfCmd.Stderr = io.MultiWriter(os.Stderr, &stderr)
+	tfCmd.Stdin = os.Stdin
+
+	stdout, err := tfCmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := tfCmd.Start(); err != nil {
+		return nil, err
+	}
+
+	result, parseErr := ParseEvents(stdout, out)
+	if parseErr != nil {
+		// Keep draining so terraform never blocks on a full pipe
+		io.Copy(io.Discard, stdout)
+	}
+	err = tfCmd.Wait()
+	if result != nil {
+		result.Stderr = stderr.String()
+	}
+	if err != nil {
+		return result, err
+	}
+	return result, parseErr
+}
+
+// OutputTerraform runs terraform with the given arguments in dir and returns
+// its stdout. Stderr is passed through to the user.
+func OutputTerraform(dir string, args ...string) ([]byte, error) {
+	tfCmd := TerraformCommand(dir, args...)
+	tfCmd.Stderr = os.Stderr
+	return tfCmd.Output()
+}
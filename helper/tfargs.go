@@ -0,0 +1,149 @@
+package helper
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// TerraformInputs records the arguments cloudtm forwarded to Terraform when
+// a version was created, so rollback can replay the same inputs
+type TerraformInputs struct {
+	Args     []string `json:"args"`
+	VarFiles []string `json:"varFiles,omitempty"`
+}
+
+// valueFlags are Terraform flags that take a value, which may be given as
+// the next argument ("-var-file prod.tfvars") instead of after "="
+var valueFlags = map[string]bool{
+	"var": true, "var-file": true, "target": true, "replace": true,
+	"parallelism": true, "lock-timeout": true, "state": true, "state-out": true, "backup": true,
+}
+
+// savedPlanFlags are the plan flags Terraform also accepts when applying a
+// saved plan; variables, targets and refresh options are part of the plan
+var savedPlanFlags = map[string]bool{
+	"parallelism": true, "lock": true, "lock-timeout": true, "no-color": true,
+	"compact-warnings": true, "state": true, "state-out": true, "backup": true,
+}
+
+// splitFlag returns the name and value of a flag argument ("-name=value",
+// "--name=value" or "-name"), or ok=false for non-flag arguments
+func splitFlag(arg string) (name, value string, ok bool) {
+	if !strings.HasPrefix(arg, "-") || arg == "-" || arg == "--" {
+		return "", "", false
+	}
+	name = strings.TrimLeft(arg, "-")
+	name, value, _ = strings.Cut(name, "=")
+	return name, value, true
+}
+
+// NormalizeArgs rewrites Terraform flags to the single-argument "-name=value"
+// form so each flag is one element of the list
+func NormalizeArgs(args []string) []string {
+	var normalized []string
+	for i := 0; i < len(args); i++ {
+		name, _, ok := splitFlag(args[i])
+		if !ok {
+			normalized = append(normalized, args[i])
+			continue
+		}
+		if valueFlags[name] && !strings.Contains(args[i], "=") && i+1 < len(args) {
+			normalized = append(normalized, "-"+name+"="+args[i+1])
+			i++
+			continue
+		}
+		normalized = append(normalized, "-"+strings.TrimLeft(args[i], "-"))
+	}
+	return normalized
+}
+
+// RedactVarValues returns args with the values of -var arguments dropped,
+// keeping the variable names ("-var=name=value" becomes "-var=name", and
+// "--var name=value" becomes "--var name")
+func RedactVarValues(args []string) []string {
+	redacted := append([]string(nil), args...)
+	for i, arg := range redacted {
+		name, value, ok := splitFlag(arg)
+		if !ok || name != "var" {
+			continue
+		}
+		if flag, _, hasValue := strings.Cut(arg, "="); hasValue {
+			varName, _, _ := strings.Cut(value, "=")
+			redacted[i] = flag + "=" + varName
+		} else if i+1 < len(redacted) {
+			redacted[i+1], _, _ = strings.Cut(redacted[i+1], "=")
+		}
+	}
+	return redacted
+}
+
+// NewTerraformInputs records normalized arguments and the var-files among
+// them. Metadata is stored unencrypted, so only the names of -var arguments
+// are kept; their values are captured with the version's variables.
+func NewTerraformInputs(args []string) *TerraformInputs {
+	if len(args) == 0 {
+		return nil
+	}
+	inputs := &TerraformInputs{Args: RedactVarValues(args)}
+	for _, arg := range args {
+		if name, value, ok := splitFlag(arg); ok && name == "var-file" {
+			inputs.VarFiles = append(inputs.VarFiles, value)
+		}
+	}
+	return inputs
+}
+
+// SavedPlanArgs returns the arguments that also apply to 'terraform apply' of
+// a saved plan
+func SavedPlanArgs(args []string) []string {
+	var filtered []string
+	for _, arg := range args {
+		if name, _, ok := splitFlag(arg); ok && savedPlanFlags[name] {
+			filtered = append(filtered, arg)
+		}
+	}
+	return filtered
+}
+
+// ReplayArgs returns the var-file arguments of recorded inputs for a
+// Terraform run in runDir, and -var arguments recorded with their values by
+// versions created before values were left out. Relative var-files are
+// looked up in runDir first and then in projectDir; var-files found in
+// neither are returned as missing and left out.
+func (in *TerraformInputs) ReplayArgs(projectDir, runDir string) (args, missing []string) {
+	if in == nil {
+		return nil, nil
+	}
+	for _, arg := range in.Args {
+		name, value, ok := splitFlag(arg)
+		switch {
+		case !ok:
+		case name == "var" && strings.Contains(value, "="):
+			args = append(args, arg)
+		case name == "var-file":
+			path, found := findVarFile(value, projectDir, runDir)
+			if !found {
+				missing = append(missing, value)
+				continue
+			}
+			args = append(args, "-var-file="+path)
+		}
+	}
+	return args, missing
+}
+
+func findVarFile(path, projectDir, runDir string) (string, bool) {
+	if filepath.IsAbs(path) {
+		_, err := os.Stat(path)
+		return path, err == nil
+	}
+	if _, err := os.Stat(filepath.Join(runDir, path)); err == nil {
+		return path, true
+	}
+	abs := filepath.Join(projectDir, path)
+	if _, err := os.Stat(abs); err == nil {
+		return abs, true
+	}
+	return path, false
+}
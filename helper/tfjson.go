@@ -0,0 +1,190 @@
+package helper
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// TerraformEvent is a single message of Terraform's machine-readable UI (-json)
+type TerraformEvent struct {
+	Level      string         `json:"@level"`
+	Message    string         `json:"@message"`
+	Module     string         `json:"@module"`
+	Timestamp  string         `json:"@timestamp"`
+	Type       string         `json:"type"`
+	Hook       *EventHook     `json:"hook,omitempty"`
+	Change     *EventChange   `json:"change,omitempty"`
+	Changes    *ChangeSummary `json:"changes,omitempty"`
+	Diagnostic *Diagnostic    `json:"diagnostic,omitempty"`
+}
+
+// EventResource identifies the resource an event refers to
+type EventResource struct {
+	Addr         string `json:"addr"`
+	Module       string `json:"module"`
+	ResourceType string `json:"resource_type"`
+	ResourceName string `json:"resource_name"`
+}
+
+// EventHook is the payload of apply_start, apply_complete and apply_errored events
+type EventHook struct {
+	Resource EventResource `json:"resource"`
+	Action   string        `json:"action"`
+	IDKey    string        `json:"id_key"`
+	IDValue  string        `json:"id_value"`
+}
+
+// EventChange is the payload of planned_change and resource_drift events
+type EventChange struct {
+	Resource EventResource `json:"resource"`
+	Action   string        `json:"action"`
+	Reason   string        `json:"reason"`
+}
+
+// ChangeSummary is the payload of the change_summary event
+type ChangeSummary struct {
+	Add       int    `json:"add"`
+	Change    int    `json:"change"`
+	Remove    int    `json:"remove"`
+	Import    int    `json:"import"`
+	Operation string `json:"operation"`
+}
+
+// HasChanges reports whether the summary contains any resource changes
+func (s *ChangeSummary) HasChanges() bool {
+	return s != nil && (s.Add != 0 || s.Change != 0 || s.Remove != 0)
+}
+
+// Diagnostic is an error or warning reported by Terraform
+type Diagnostic struct {
+	Severity string `json:"severity"`
+	Summary  string `json:"summary"`
+	Detail   string `json:"detail"`
+	Address  string `json:"address,omitempty"`
+}
+
+// ResourceChange records the action taken (or planned) for a single resource
+type ResourceChange struct {
+	Address string `json:"address"`
+	Action  string `json:"action"`
+}
+
+// RunResult collects the structured data of a Terraform -json run
+type RunResult struct {
+	Summary     *ChangeSummary
+	Planned     []ResourceChange
+	Applied     []ResourceChange
+	Failed      []ResourceChange
+	Diagnostics []Diagnostic
+	Stderr      string // Output terraform wrote to stderr (crashes, panics)
+}
+
+// Errors returns the error diagnostics of the run
+func (r *RunResult) Errors() []Diagnostic {
+	var errs []Diagnostic
+	for _, d := range r.Diagnostics {
+		if d.Severity == "error" {
+			errs = append(errs, d)
+		}
+	}
+	return errs
+}
+
+// AppliedSummary counts the changes Terraform completed, which for a failed
+// apply may be fewer than were planned
+func (r *RunResult) AppliedSummary() ChangeSummary {
+	summary := ChangeSummary{Operation: "apply"}
+	for _, c := range r.Applied {
+		switch c.Action {
+		case "create":
+			summary.Add++
+		case "update":
+			summary.Change++
+		case "delete":
+			summary.Remove++
+		}
+	}
+	return summary
+}
+
+// ParseEvents reads Terraform's JSON event stream from r, collects the result
+// and writes a human readable rendering of each event to out
+func ParseEvents(r io.Reader, out io.Writer) (*RunResult, error) {
+	result := &RunResult{}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		var event TerraformEvent
+		if err := json.Unmarshal([]byte(line), &event); err != nil || event.Type == "" {
+			// Not an event (e.g. provider output) — pass it through untouched
+			fmt.Fprintln(out, line)
+			continue
+		}
+
+		switch event.Type {
+		case "planned_change":
+			if event.Change != nil {
+				result.Planned = append(result.Planned, ResourceChange{Address: event.Change.Resource.Addr, Action: event.Change.Action})
+			}
+		case "apply_complete":
+			if event.Hook != nil {
+				result.Applied = append(result.Applied, ResourceChange{Address: event.Hook.Resource.Addr, Action: event.Hook.Action})
+			}
+		case "apply_errored":
+			if event.Hook != nil {
+				result.Failed = append(result.Failed, ResourceChange{Address: event.Hook.Resource.Addr, Action: event.Hook.Action})
+			}
+		case "change_summary":
+			result.Summary = event.Changes
+		case "diagnostic":
+			if event.Diagnostic != nil {
+				result.Diagnostics = append(result.Diagnostics, *event.Diagnostic)
+			}
+		}
+
+		RenderEvent(out, event)
+	}
+
+	return result, scanner.Err()
+}
+
+// RenderEvent writes a human readable line for a Terraform event
+func RenderEvent(out io.Writer, event TerraformEvent) {
+	switch event.Type {
+	case "version", "apply_progress":
+		// Noise for humans
+	case "diagnostic":
+		if event.Diagnostic == nil {
+			fmt.Fprintln(out, event.Message)
+			return
+		}
+		d := event.Diagnostic
+		label := "Warning"
+		if d.Severity == "error" {
+			label = "Error"
+		}
+		fmt.Fprintf(out, "\n│ %s: %s\n", label, d.Summary)
+		if d.Address != "" {
+			fmt.Fprintf(out, "│\n│   with %s\n", d.Address)
+		}
+		if d.Detail != "" {
+			fmt.Fprintln(out, "│")
+			for _, line := range strings.Split(d.Detail, "\n") {
+				fmt.Fprintf(out, "│ %s\n", line)
+			}
+		}
+		fmt.Fprintln(out)
+	case "change_summary":
+		fmt.Fprintf(out, "\n%s\n", event.Message)
+	default:
+		if event.Message != "" {
+			fmt.Fprintln(out, event.Message)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package helper
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// VariablesFileName holds the effective root module variables of a version
+// in versions/<version>/
+const VariablesFileName = "variables.json"
+
+// VersionVariables is the effective variable set an apply ran with, whatever
+// its source: .tfvars files, -var flags, TF_VAR_* environment variables or
+// defaults
+type VersionVariables struct {
+	Variables map[string]CapturedVariable `json:"variables"`
+}
+
+// CapturedVariable is the value of one root module variable. Sensitive
+// values are only kept when the store encrypts snapshot content; otherwise
+// Redacted is set and Value is empty.
+type CapturedVariable struct {
+	Value     json.RawMessage `json:"value,omitempty"`
+	Sensitive bool            `json:"sensitive,omitempty"`
+	Redacted  bool            `json:"redacted,omitempty"`
+}
+
+// PlanVariable is the value of a root module variable in a plan
+type PlanVariable struct {
+	Value json.RawMessage `json:"value"`
+}
+
+// CaptureVariables returns the plan's root module variables. Values of
+// variables declared sensitive are redacted unless keepSensitive is set.
+func (p *Plan) CaptureVariables(keepSensitive bool) *VersionVariables {
+	if len(p.Variables) == 0 {
+		return nil
+	}
+	vars := &VersionVariables{Variables: make(map[string]CapturedVariable)}
+	for name, v := range p.Variables {
+		captured := CapturedVariable{Value: v.Value}
+		if p.Configuration.RootModule.Variables[name].Sensitive {
+			captured.Sensitive = true
+			if !keepSensitive {
+				captured.Value, captured.Redacted = nil, true
+			}
+		}
+		vars.Variables[name] = captured
+	}
+	return vars
+}
+
+// Redact drops the values of sensitive variables
+func (v *VersionVariables) Redact() {
+	for name, captured := range v.Variables {
+		if captured.Sensitive && !captured.Redacted {
+			v.Variables[name] = CapturedVariable{Sensitive: true, Redacted: true}
+		}
+	}
+}
+
+// TFVars renders the captured values as a .tfvars.json file. Redacted
+// variables are left out and returned, sorted, so they can come from the
+// environment instead.
+func (v *VersionVariables) TFVars() ([]byte, []string, error) {
+	values := make(map[string]json.RawMessage)
+	var redacted []string
+	for name, captured := range v.Variables {
+		if captured.Redacted {
+			redacted = append(redacted, name)
+			continue
+		}
+		values[name] = captured.Value
+	}
+	sort.Strings(redacted)
+	data, err := json.MarshalIndent(values, "", "  ")
+	return data, redacted, err
+}
+
+// SaveVariables stores the variables of a version
+func SaveVariables(store SnapshotStore, version string, vars *VersionVariables) error {
+	data, err := json.MarshalIndent(vars, "", "  ")
+	if err != nil {
+		return err
+	}
+	return store.Put("versions/"+version+"/"+VariablesFileName, bytes.NewReader(data))
+}
+
+// LoadVariables reads the variables stored with a version. Versions created
+// before variables were captured return an error matching fs.ErrNotExist.
+func LoadVariables(store SnapshotStore, version string) (*VersionVariables, error) {
+	r, err := store.Get("versions/" + version + "/" + VariablesFileName)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return parseVariables(version, data)
+}
+
+func parseVariables(version string, data []byte) (*VersionVariables, error) {
+	var vars VersionVariables
+	if err := json.Unmarshal(data, &vars); err != nil {
+		return nil, fmt.Errorf("%s variables: %w", version, err)
+	}
+	return &vars, nil
+}
+
+// redactVariablesFile drops sensitive values from a stored variables.json
+func redactVariablesFile(data []byte) ([]byte, error) {
+	vars, err := parseVariables("", data)
+	if err != nil {
+		return nil, err
+	}
+	vars.Redact()
+	return json.MarshalIndent(vars, "", "  ")
+}
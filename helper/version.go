@@ -20,7 +20,7 @@ func UpdateCurrentVersion(cloudtmDir, version string, status bool) error {
 		return err
 	}
 
-	return os.WriteFile(currentFile, currentJSON, 0644)
+	return WriteFileAtomic(currentFile, currentJSON, 0644)
 }
 
 // GetCurrentVersion reads the current version and status from current.json
@@ -67,7 +67,7 @@ func SetCurrentStatus(cloudtmDir string, status bool) error {
 		return err
 	}
 
-	return os.WriteFile(currentFile, currentJSON, 0644)
+	return WriteFileAtomic(currentFile, currentJSON, 0644)
 }
 
 // UpdateRollbackVersion updates the rollback.json file with the rollback version
@@ -83,7 +83,7 @@ func UpdateRollbackVersion(cloudtmDir, version string) error {
 		return err
 	}
 
-	return os.WriteFile(rollbackFile, rollbackJSON, 0644)
+	return WriteFileAtomic(rollbackFile, rollbackJSON, 0644)
 }
 
 // GetRollbackVersion reads the rollback version from rollback.json